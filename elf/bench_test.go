@@ -0,0 +1,144 @@
+package elf
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The benchmarks run on gobjdump, cmd/go and the binaries listed in
+// $GOBJDUMP_BENCH, separated like $PATH, each opened mapped in memory and
+// read with ReadAt, e.g.
+//
+//	GOBJDUMP_BENCH=/usr/local/bin/big go test -run - -bench . -benchmem ./elf
+
+var benchBins struct {
+	once  sync.Once
+	paths []string
+	dir   string
+}
+
+// benchBinaries returns the paths of the binaries to benchmark, built once
+// for all the benchmarks.
+func benchBinaries(b *testing.B) []string {
+	benchBins.once.Do(func() {
+		dir, err := os.MkdirTemp("", "gobjdump-bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchBins.dir = dir
+		for _, p := range [][2]string{{"gobjdump", ".."}, {"go", "cmd/go"}} {
+			out := filepath.Join(dir, p[0])
+			cmd := exec.Command("go", "build", "-o", out, p[1])
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				b.Fatalf("building %s: %v", p[1], err)
+			}
+			benchBins.paths = append(benchBins.paths, out)
+		}
+		benchBins.paths = append(benchBins.paths, filepath.SplitList(os.Getenv("GOBJDUMP_BENCH"))...)
+	})
+	if benchBins.paths == nil {
+		b.Skip("no binaries")
+	}
+	return benchBins.paths
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if benchBins.dir != "" {
+		os.RemoveAll(benchBins.dir)
+	}
+	os.Exit(code)
+}
+
+// benchOpen runs bench on each binary, opened mapped and not.
+func benchOpen(b *testing.B, bench func(b *testing.B, path string, mmap bool)) {
+	for _, path := range benchBinaries(b) {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		for _, mmap := range []bool{true, false} {
+			mode := "readat"
+			if mmap {
+				mode = "mmap"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				b.ReportAllocs()
+				bench(b, path, mmap)
+			})
+		}
+	}
+}
+
+func benchFile(b *testing.B, path string, mmap bool) *ELF_Info {
+	f, err := openFile(path, mmap)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return f
+}
+
+func BenchmarkOpen(b *testing.B) {
+	benchOpen(b, func(b *testing.B, path string, mmap bool) {
+		for i := 0; i < b.N; i++ {
+			benchFile(b, path, mmap).Close()
+		}
+	})
+}
+
+// BenchmarkFirstFunc opens the binary to dump a single function, like
+// gobjdump pcsp -f main.main.
+func BenchmarkFirstFunc(b *testing.B) {
+	benchOpen(b, func(b *testing.B, path string, mmap bool) {
+		for i := 0; i < b.N; i++ {
+			f := benchFile(b, path, mmap)
+			if _, err := f.PCSP("main.main"); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+}
+
+func BenchmarkFuncLookup(b *testing.B) {
+	benchOpen(b, func(b *testing.B, path string, mmap bool) {
+		f := benchFile(b, path, mmap)
+		defer f.Close()
+		names, err := f.SelectFuncs(FuncSelector{All: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := f.Func(names[i*7919%len(names)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFuncsByFile(b *testing.B) {
+	benchOpen(b, func(b *testing.B, path string, mmap bool) {
+		for i := 0; i < b.N; i++ {
+			f := benchFile(b, path, mmap)
+			if _, err := f.FuncsByFile(); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+}
+
+func BenchmarkTypes(b *testing.B) {
+	benchOpen(b, func(b *testing.B, path string, mmap bool) {
+		for i := 0; i < b.N; i++ {
+			f := benchFile(b, path, mmap)
+			if _, err := f.Types(true); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package elf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+const (
+	SEC_BUILDINFO       = ".go.buildinfo"
+	SEC_MACHO_BUILDINFO = "__go_buildinfo"
+)
+
+// buildInfoMagic starts the build info, see debug/buildinfo.
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// build info flags
+const (
+	buildInfoBigEndian = 1 << 0
+	buildInfoInline    = 1 << 1 // go1.18+, the strings follow the header
+)
+
+// findBuildInfo returns the build info header, from its section or else by
+// looking for its magic in the data, it is aligned to 16 bytes.
+func (e *ELF_Info) findBuildInfo() []byte {
+	for _, s := range e.secs {
+		if s.name == SEC_BUILDINFO || s.name == SEC_MACHO_BUILDINFO {
+			if b := s.contents(); bytes.HasPrefix(b, buildInfoMagic) {
+				return b
+			}
+		}
+	}
+	for _, s := range e.secs {
+		if !s.writable {
+			continue
+		}
+		b := s.contents()
+		for off := 0; ; {
+			i := bytes.Index(b[off:], buildInfoMagic)
+			if i < 0 {
+				break
+			}
+			if (off+i)%16 == 0 {
+				return b[off+i:]
+			}
+			off += i + 1
+		}
+	}
+	return nil
+}
+
+// loadBuildInfo reads the Go version and the module information the binary
+// was built with, if they are present.
+func (e *ELF_Info) loadBuildInfo() error {
+	b := e.findBuildInfo()
+	if len(b) < 32 {
+		return &SectionNotFoundError{SEC_BUILDINFO, 0}
+	}
+	var vers, mod string
+	if flags := b[15]; flags&buildInfoInline != 0 {
+		var ok bool
+		if vers, b, ok = varintString(b[32:]); ok {
+			mod, _, _ = varintString(b)
+		}
+	} else {
+		// pointers to the version and modinfo strings
+		d := decoder{order: binary.LittleEndian, ptrSize: int(b[14])}
+		if flags&buildInfoBigEndian != 0 {
+			d.order = binary.BigEndian
+		}
+		if d.ptrSize != 4 && d.ptrSize != 8 {
+			return fmt.Errorf("%w: build info pointer size %d", ErrMalformed, d.ptrSize)
+		}
+		vers = e.stringAt(&d, d.uintptr(b[16:]))
+		mod = e.stringAt(&d, d.uintptr(b[16+d.ptrSize:]))
+	}
+	if vers == "" {
+		return fmt.Errorf("%w: no Go version in build info", ErrMalformed)
+	}
+	// the module information is framed by 16 byte sentinels
+	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
+		mod = mod[16 : len(mod)-16]
+	} else {
+		mod = ""
+	}
+	e.buildVersion, e.modinfo = vers, mod
+	e.release = parseRelease(vers)
+	return nil
+}
+
+// varintString decodes a string prefixed with its length as a uvarint from
+// b, returning the rest of b.
+func varintString(b []byte) (string, []byte, bool) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || l > uint64(len(b)-n) {
+		return "", nil, false
+	}
+	return string(b[n : n+int(l)]), b[n+int(l):], true
+}
+
+// stringAt returns the string which header is at addr, "" if it is not in
+// the file.
+func (e *ELF_Info) stringAt(d *decoder, addr uint64) string {
+	h := e.mem(addr)
+	if len(h) < 2*d.ptrSize {
+		return ""
+	}
+	data, n := d.uintptr(h), d.uintptr(h[d.ptrSize:])
+	b := e.mem(data)
+	if uint64(len(b)) < n {
+		return ""
+	}
+	return string(b[:n])
+}
+
+// PrintBuildInfo prints the Go version, the main module, the dependencies
+// and the build settings of the binary, in the format of go version -m.
+func (e *ELF_Info) PrintBuildInfo(out io.Writer) error {
+	bi, err := e.BuildInfo()
+	return writeText(out, bi, err)
+}
+
+// BuildInfo returns the Go version, the main module, the dependencies and
+// the build settings of the binary.
+func (e *ELF_Info) BuildInfo() (bi *BuildInfo, err error) {
+	defer catch(&err)
+	if e.buildInfoErr != nil {
+		return nil, e.buildInfoErr
+	}
+	dbi, err := debug.ParseBuildInfo(e.modinfo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	module := func(m *debug.Module) BuildModule {
+		bm := BuildModule{Path: m.Path, Version: m.Version, Sum: m.Sum}
+		if r := m.Replace; r != nil {
+			bm.Replace = &BuildModule{Path: r.Path, Version: r.Version, Sum: r.Sum}
+		}
+		return bm
+	}
+	bi = &BuildInfo{GoVersion: e.buildVersion, Path: dbi.Path, Main: module(&dbi.Main)}
+	for _, d := range dbi.Deps {
+		bi.Deps = append(bi.Deps, module(d))
+	}
+	for _, s := range dbi.Settings {
+		bi.Settings = append(bi.Settings, BuildSetting{s.Key, s.Value})
+	}
+	return bi, nil
+}
@@ -0,0 +1,159 @@
+package elf
+
+import (
+	felf "debug/elf"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Core is an ELF core file dumped by Linux for a process of a Go binary.
+type Core struct {
+	f       *felf.File
+	loads   []*felf.Prog
+	threads []Thread
+	entry   uint64 // AT_ENTRY, the entry point of the binary once loaded
+}
+
+// note types, see linux/elf.h
+const (
+	ntPrstatus = 1
+	ntAuxv     = 6
+	atEntry    = 9 // in the auxiliary vector
+)
+
+// OpenCore opens the core file name.
+func OpenCore(name string) (c *Core, err error) {
+	f, err := felf.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Type != felf.ET_CORE {
+		f.Close()
+		return nil, fmt.Errorf("%s: not a core file", name)
+	}
+	c = &Core{f: f}
+	for _, p := range f.Progs {
+		switch p.Type {
+		case felf.PT_LOAD:
+			c.loads = append(c.loads, p)
+		case felf.PT_NOTE:
+			if err = c.readNotes(p); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Close closes the core file.
+func (c *Core) Close() error {
+	return c.f.Close()
+}
+
+// readNotes reads the threads and the entry point from the notes of p.
+func (c *Core) readNotes(p *felf.Prog) error {
+	b, err := io.ReadAll(p.Open())
+	if err != nil {
+		return err
+	}
+	order := c.f.ByteOrder
+	align := func(n uint32) uint32 { return (n + 3) &^ 3 }
+	for len(b) >= 12 {
+		namesz, descsz, typ := order.Uint32(b), order.Uint32(b[4:]), order.Uint32(b[8:])
+		b = b[12:]
+		if uint64(align(namesz))+uint64(align(descsz)) > uint64(len(b)) {
+			return errors.New("truncated note")
+		}
+		desc := b[align(namesz):][:descsz]
+		b = b[align(namesz)+align(descsz):]
+		switch typ {
+		case ntPrstatus:
+			if t, ok := c.prstatus(desc); ok {
+				c.threads = append(c.threads, t)
+			}
+		case ntAuxv:
+			if c.f.Class != felf.ELFCLASS64 {
+				break
+			}
+			for ; len(desc) >= 16; desc = desc[16:] {
+				if order.Uint64(desc) == atEntry {
+					c.entry = order.Uint64(desc[8:])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// prstatus decodes the thread of an elf_prstatus note, on amd64 and arm64.
+func (c *Core) prstatus(desc []byte) (Thread, bool) {
+	// siginfo, cursig, sigpend, sighold, then pid, ppid, pgrp, sid and
+	// 4 timevals before the registers
+	const pid, regs = 32, 112
+	reg := func(i int) uint64 {
+		return c.f.ByteOrder.Uint64(desc[regs+8*i:])
+	}
+	var t Thread
+	switch c.f.Machine {
+	case felf.EM_X86_64:
+		if len(desc) < regs+27*8 {
+			return t, false
+		}
+		// user_regs_struct: rip is 16th, rsp 19th
+		t.PC, t.SP = reg(16), reg(19)
+	case felf.EM_AARCH64:
+		if len(desc) < regs+34*8 {
+			return t, false
+		}
+		// user_pt_regs: x0-x30, sp, pc
+		t.LR, t.SP, t.PC = reg(30), reg(31), reg(32)
+	default:
+		return t, false
+	}
+	t.ID = int(int32(c.f.ByteOrder.Uint32(desc[pid:])))
+	return t, true
+}
+
+// ReadAt reads the memory of the process at addr from the core file. It
+// fails if some of it was not dumped.
+func (c *Core) ReadAt(p []byte, addr int64) (n int, err error) {
+	for n < len(p) {
+		a := uint64(addr) + uint64(n)
+		var seg *felf.Prog
+		for _, s := range c.loads {
+			if a >= s.Vaddr && a < s.Vaddr+s.Filesz {
+				seg = s
+				break
+			}
+		}
+		if seg == nil {
+			return n, fmt.Errorf("address %#x not in core file", a)
+		}
+		m := len(p) - n
+		if rest := seg.Vaddr + seg.Filesz - a; uint64(m) > rest {
+			m = int(rest)
+		}
+		if _, err := seg.ReadAt(p[n:n+m], int64(a-seg.Vaddr)); err != nil {
+			return n, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// Threads returns the threads of the process, the first being the one that
+// received the signal which dumped the core.
+func (c *Core) Threads() []Thread {
+	return c.threads
+}
+
+// Process returns the process dumped in c, e being its binary.
+func (c *Core) Process(e *ELF_Info) *Process {
+	p := &Process{Mem: c, Threads: c.threads}
+	if o, ok := e.obj.(*elfFile); ok && c.entry != 0 {
+		p.Bias = c.entry - o.f.Entry
+	}
+	return p
+}
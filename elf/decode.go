@@ -0,0 +1,104 @@
+package elf
+
+import "encoding/binary"
+
+// decoder reads integers in the byte order and pointer size of the target
+// from the raw contents of the binary.
+type decoder struct {
+	order   binary.ByteOrder
+	ptrSize int
+}
+
+func (d *decoder) uint32(b []byte) uint32 {
+	return d.order.Uint32(b)
+}
+
+func (d *decoder) uintptr(b []byte) uint64 {
+	if d.ptrSize == 4 {
+		return uint64(d.order.Uint32(b))
+	}
+	return d.order.Uint64(b)
+}
+
+// slice is the header of a slice (or string, with cap unused) in the target.
+type slice struct {
+	data, len, cap uint64
+}
+
+// field is a struct field decoded by structReader, kept for printing.
+type field struct {
+	name  string
+	value uint64
+}
+
+// structReader decodes the fields of a runtime struct one after another,
+// applying the alignment rules of the target.
+type structReader struct {
+	*decoder
+	b      []byte
+	off    int
+	short  bool    // read past the end of b
+	fields []field // the fields read so far
+}
+
+func (r *structReader) next(size, align int) []byte {
+	r.off = (r.off + align - 1) &^ (align - 1)
+	if r.off+size > len(r.b) {
+		r.short = true
+		return make([]byte, size)
+	}
+	b := r.b[r.off : r.off+size]
+	r.off += size
+	return b
+}
+
+func (r *structReader) record(name string, v uint64) {
+	r.fields = append(r.fields, field{name, v})
+}
+
+func (r *structReader) word(name string) uint64 {
+	v := r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	r.record(name, v)
+	return v
+}
+
+// slice reads a slice header, only its data pointer is recorded.
+func (r *structReader) slice(name string) slice {
+	var s slice
+	s.data = r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	s.len = r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	s.cap = r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	r.record(name, s.data)
+	return s
+}
+
+// string reads a string header, only its data pointer is recorded.
+func (r *structReader) string(name string) slice {
+	var s slice
+	s.data = r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	s.len = r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	s.cap = s.len
+	r.record(name, s.data)
+	return s
+}
+
+func (r *structReader) uint16(name string) uint16 {
+	v := r.order.Uint16(r.next(2, 2))
+	r.record(name, uint64(v))
+	return v
+}
+
+func (r *structReader) uint8(name string) uint8 {
+	v := r.next(1, 1)[0]
+	r.record(name, uint64(v))
+	return v
+}
+
+// bitvector reads a runtime.bitvector, only its data pointer is recorded.
+func (r *structReader) bitvector(name string) bitvector {
+	var v bitvector
+	v.n = int32(r.uint32(r.next(4, r.ptrSize)))
+	v.bytedata = r.uintptr(r.next(r.ptrSize, r.ptrSize))
+	r.record(name, v.bytedata)
+	return v
+}
@@ -0,0 +1,475 @@
+package elf
+
+import (
+	"debug/dwarf"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// debugInfo is the DWARF of a binary, with the sections debug/dwarf does
+// not decode.
+type debugInfo struct {
+	mu       sync.Mutex // guards the type cache of d and types
+	d        *dwarf.Data
+	loc      []byte                // .debug_loc, before DWARF 5
+	loclists []byte                // .debug_loclists
+	addr     []byte                // .debug_addr
+	subprogs map[uint64]subprogram // by entry pc, built on first use under ELF_Info.mu
+	types    map[dwarf.Offset]dwarfType
+}
+
+// subprogram is the DWARF entry of a function, at off in its unit.
+type subprogram struct {
+	off  dwarf.Offset
+	unit *dwarfUnit
+}
+
+// dwarfUnit is what the location lists of a compile unit need.
+type dwarfUnit struct {
+	addrBase uint64 // of the unit in .debug_addr, 0 before DWARF 5
+	lowpc    uint64 // the base address of the location lists before DWARF 5
+}
+
+// dwarfType is the name and size of a type found in DWARF.
+type dwarfType struct {
+	name string
+	size int64
+}
+
+// typ returns the type at off, as d.Type does but safe for concurrent use.
+func (di *debugInfo) typ(off dwarf.Offset) (dwarf.Type, error) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	return di.d.Type(off)
+}
+
+// dwarf returns the debugging information of the binary, read on first use.
+func (e *ELF_Info) dwarf() (*debugInfo, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.debug == nil && e.debugErr == nil {
+		d, err := e.obj.dwarf()
+		if err != nil {
+			e.debugErr = fmt.Errorf("reading DWARF: %w", err)
+			return nil, e.debugErr
+		}
+		e.debug = &debugInfo{
+			d:        d,
+			loc:      e.obj.debugSection("loc"),
+			loclists: e.obj.debugSection("loclists"),
+			addr:     e.obj.debugSection("addr"),
+		}
+	}
+	return e.debug, e.debugErr
+}
+
+// DWARF expression and location list encodings, see the DWARF 5 standard.
+const (
+	dwOpConsts       = 0x11
+	dwOpPlus         = 0x22
+	dwOpPlusUconst   = 0x23
+	dwOpReg0         = 0x50
+	dwOpReg31        = 0x6f
+	dwOpRegx         = 0x90
+	dwOpFbreg        = 0x91
+	dwOpPiece        = 0x93
+	dwOpCallFrameCFA = 0x9c
+
+	dwLLEEndOfList    = 0
+	dwLLEBaseAddressx = 1
+	dwLLEStartxEndx   = 2
+	dwLLEStartxLength = 3
+	dwLLEOffsetPair   = 4
+	dwLLEDefault      = 5
+	dwLLEBaseAddress  = 6
+	dwLLEStartEnd     = 7
+	dwLLEStartLength  = 8
+)
+
+// stackVar is a piece of a parameter or local variable of a function held
+// in its frame in [lo, hi), at fp+fp, fp being the sp of the caller like
+// the canonical frame address of DWARF.
+type stackVar struct {
+	name, typ string
+	fp, size  int64
+	off       int64 // of the piece in the variable
+	lo, hi    uint64
+	inlined   bool // declared in a call inlined into the function
+}
+
+// subprograms returns the DWARF entries of the functions by entry pc,
+// indexing them on first use.
+func (e *ELF_Info) subprograms(di *debugInfo) map[uint64]subprogram {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if di.subprogs != nil {
+		return di.subprogs
+	}
+	di.subprogs = map[uint64]subprogram{}
+	r := di.d.Reader()
+	var unit *dwarfUnit
+	for {
+		ent, err := r.Next()
+		if err != nil || ent == nil {
+			break
+		}
+		switch ent.Tag {
+		case dwarf.TagCompileUnit:
+			unit = &dwarfUnit{}
+			if b, ok := ent.Val(dwarf.AttrAddrBase).(int64); ok {
+				unit.addrBase = uint64(b)
+			}
+			unit.lowpc, _ = ent.Val(dwarf.AttrLowpc).(uint64)
+			continue
+		case dwarf.TagSubprogram:
+			if lowpc, ok := ent.Val(dwarf.AttrLowpc).(uint64); ok && ent.Children {
+				di.subprogs[lowpc] = subprogram{ent.Offset, unit}
+			}
+		}
+		if ent.Children {
+			r.SkipChildren()
+		}
+	}
+	return di.subprogs
+}
+
+// dwarfFrame is what frameVars needs to know of a function, r reading its
+// DWARF.
+type dwarfFrame struct {
+	*debugInfo
+	*dwarfUnit
+	r      *dwarf.Reader
+	lo, hi uint64 // the pcs of the function
+	vars   []stackVar
+}
+
+// frameVars returns the parameters and local variables of f held in its
+// frame, with the inlined ones, from the DWARF of the binary. It returns
+// nil if the binary has no DWARF or f is not found in it.
+func (e *ELF_Info) frameVars(f *_func) []stackVar {
+	di, err := e.dwarf()
+	if err != nil {
+		return nil
+	}
+	sp, ok := e.subprograms(di)[f.entry]
+	if !ok {
+		return nil
+	}
+	r := di.d.Reader()
+	r.Seek(sp.off)
+	if ent, err := r.Next(); err != nil || ent == nil {
+		return nil
+	}
+	fr := &dwarfFrame{debugInfo: di, dwarfUnit: sp.unit, r: di.d.Reader(), lo: f.entry, hi: e.funcEnd(f)}
+	e.scopeVars(fr, r, false)
+	return fr.vars
+}
+
+// scopeVars adds the variables of the scope which children r reads next to
+// fr, up to the end of the scope, inlined telling whether the scope is in
+// an inlined call.
+func (e *ELF_Info) scopeVars(fr *dwarfFrame, r *dwarf.Reader, inlined bool) {
+	for {
+		ent, err := r.Next()
+		if err != nil || ent == nil || ent.Tag == 0 {
+			return
+		}
+		switch ent.Tag {
+		case dwarf.TagFormalParameter, dwarf.TagVariable:
+			e.entryVars(fr, ent, inlined)
+		case dwarf.TagLexDwarfBlock, dwarf.TagInlinedSubroutine:
+			if ent.Children {
+				e.scopeVars(fr, r, inlined || ent.Tag == dwarf.TagInlinedSubroutine)
+			}
+			continue
+		}
+		if ent.Children {
+			r.SkipChildren()
+		}
+	}
+}
+
+// entryVars adds the stack pieces of the variable ent to fr.
+func (e *ELF_Info) entryVars(fr *dwarfFrame, ent *dwarf.Entry, inlined bool) {
+	name, typ, size := fr.describe(ent)
+	if name == "" {
+		return
+	}
+	v := stackVar{name: name, typ: typ, inlined: inlined}
+	loc := ent.AttrField(dwarf.AttrLocation)
+	switch {
+	case loc == nil:
+	case loc.Class == dwarf.ClassExprLoc:
+		b, _ := loc.Val.([]byte)
+		v.lo, v.hi = 0, ^uint64(0)
+		fr.addPieces(v, size, b)
+	case loc.Class == dwarf.ClassLocListPtr:
+		off, _ := loc.Val.(int64)
+		e.locList(fr, uint64(off), func(lo, hi uint64, b []byte) {
+			v.lo, v.hi = lo, hi
+			fr.addPieces(v, size, b)
+		})
+	}
+}
+
+// describe returns the name, type and size of the variable ent, which are
+// those of its abstract origin if it is inlined.
+func (fr *dwarfFrame) describe(ent *dwarf.Entry) (name, typ string, size int64) {
+	if o, ok := ent.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok {
+		fr.r.Seek(o)
+		if origin, err := fr.r.Next(); err == nil && origin != nil {
+			ent = origin
+		}
+	}
+	name, _ = ent.Val(dwarf.AttrName).(string)
+	o, ok := ent.Val(dwarf.AttrType).(dwarf.Offset)
+	if !ok {
+		return name, "", 0
+	}
+	t := fr.typeOf(o)
+	return name, t.name, t.size
+}
+
+// typeOf returns the name and size of the type at off, caching them.
+func (fr *dwarfFrame) typeOf(off dwarf.Offset) dwarfType {
+	fr.mu.Lock()
+	t, ok := fr.types[off]
+	fr.mu.Unlock()
+	if ok {
+		return t
+	}
+	fr.r.Seek(off)
+	ent, err := fr.r.Next()
+	if err != nil || ent == nil {
+		return t
+	}
+	t.name, _ = ent.Val(dwarf.AttrName).(string)
+	if t.size, ok = ent.Val(dwarf.AttrByteSize).(int64); !ok {
+		// pointers have no size
+		if dt, err := fr.typ(off); err == nil {
+			t.size = dt.Size()
+		}
+	}
+	fr.mu.Lock()
+	if fr.types == nil {
+		fr.types = map[dwarf.Offset]dwarfType{}
+	}
+	fr.types[off] = t
+	fr.mu.Unlock()
+	return t
+}
+
+// addPieces adds to fr the pieces of the location expression b of the
+// variable v of the given size, in effect in [v.lo, v.hi), which are in the
+// frame.
+func (fr *dwarfFrame) addPieces(v stackVar, size int64, b []byte) {
+	if v.hi <= fr.lo || v.lo >= fr.hi {
+		return
+	}
+	var stack []int64
+	inFrame := false // the top of stack is an offset from the CFA
+	off := int64(0)
+	for len(b) > 0 {
+		op := b[0]
+		b = b[1:]
+		switch {
+		case op == dwOpFbreg:
+			v, n := sleb128(b)
+			b = b[n:]
+			stack, inFrame = append(stack, v), true
+		case op == dwOpCallFrameCFA:
+			stack, inFrame = append(stack, 0), true
+		case op == dwOpConsts:
+			v, n := sleb128(b)
+			b = b[n:]
+			stack = append(stack, v)
+		case op == dwOpPlusUconst && len(stack) > 0:
+			v, n := binary.Uvarint(b)
+			b = b[n:]
+			stack[len(stack)-1] += int64(v)
+		case op == dwOpPlus && len(stack) > 1:
+			stack[len(stack)-2] += stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		case op >= dwOpReg0 && op <= dwOpReg31:
+			stack, inFrame = nil, false
+		case op == dwOpRegx:
+			_, n := binary.Uvarint(b)
+			b = b[n:]
+			stack, inFrame = nil, false
+		case op == dwOpPiece:
+			n, l := binary.Uvarint(b)
+			b = b[l:]
+			if inFrame && len(stack) == 1 {
+				v.fp, v.size, v.off = stack[0], int64(n), off
+				fr.vars = append(fr.vars, v)
+			}
+			off += int64(n)
+			stack, inFrame = nil, false
+		default:
+			// not a location in the frame
+			return
+		}
+	}
+	if inFrame && len(stack) == 1 && off == 0 && size > 0 {
+		v.fp, v.size, v.off = stack[0], size, 0
+		fr.vars = append(fr.vars, v)
+	}
+}
+
+// locList calls fn with the ranges of pcs and location expressions of the
+// location list at off, in .debug_loclists from DWARF 5 on, else in
+// .debug_loc.
+func (e *ELF_Info) locList(fr *dwarfFrame, off uint64, fn func(lo, hi uint64, b []byte)) {
+	w := e.dec.ptrSize
+	if fr.addrBase == 0 {
+		b := fr.loc
+		if off >= uint64(len(b)) {
+			return
+		}
+		base := fr.lowpc
+		for b = b[off:]; len(b) >= 2*w; {
+			lo, hi := e.dec.uintptr(b), e.dec.uintptr(b[w:])
+			b = b[2*w:]
+			switch {
+			case lo == 0 && hi == 0:
+				return
+			case lo == ^uint64(0)>>(64-8*w):
+				base = hi
+				continue
+			}
+			if len(b) < 2 {
+				return
+			}
+			n := int(e.dec.order.Uint16(b))
+			if len(b) < 2+n {
+				return
+			}
+			fn(base+lo, base+hi, b[2:2+n])
+			b = b[2+n:]
+		}
+		return
+	}
+	b := fr.loclists
+	addr := func(i uint64) uint64 {
+		if o := fr.addrBase + i*uint64(w); o+uint64(w) <= uint64(len(fr.addr)) {
+			return e.dec.uintptr(fr.addr[o:])
+		}
+		return 0
+	}
+	if off >= uint64(len(b)) {
+		return
+	}
+	b = b[off:]
+	uleb := func() uint64 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			b = nil
+			return 0
+		}
+		b = b[n:]
+		return v
+	}
+	ptr := func() uint64 {
+		if len(b) < w {
+			b = nil
+			return 0
+		}
+		v := e.dec.uintptr(b)
+		b = b[w:]
+		return v
+	}
+	base := fr.lowpc
+	for len(b) > 0 {
+		kind := b[0]
+		b = b[1:]
+		var lo, hi uint64
+		switch kind {
+		case dwLLEEndOfList:
+			return
+		case dwLLEBaseAddressx:
+			base = addr(uleb())
+			continue
+		case dwLLEBaseAddress:
+			base = ptr()
+			continue
+		case dwLLEStartxEndx:
+			lo = addr(uleb())
+			hi = addr(uleb())
+		case dwLLEStartxLength:
+			lo = addr(uleb())
+			hi = lo + uleb()
+		case dwLLEOffsetPair:
+			lo = base + uleb()
+			hi = base + uleb()
+		case dwLLEDefault:
+			lo, hi = 0, ^uint64(0)
+		case dwLLEStartEnd:
+			lo = ptr()
+			hi = ptr()
+		case dwLLEStartLength:
+			lo = ptr()
+			hi = lo + uleb()
+		default:
+			return
+		}
+		n := uleb()
+		if n > uint64(len(b)) {
+			return
+		}
+		fn(lo, hi, b[:n])
+		b = b[n:]
+	}
+}
+
+// sleb128 decodes a signed LEB128 number at the start of b, returning it
+// and its length.
+func sleb128(b []byte) (int64, int) {
+	var v int64
+	var shift uint
+	for i, c := range b {
+		v |= int64(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+// stackVarsAt returns the variables of vars which piece at fp-relative
+// offset off is in the frame at some pc of [lo, hi), with the offset of the
+// word in them. The parameters of inlined calls often live in the words of
+// the arguments passed to them, so the variables of inlined calls are left
+// out if one of the function is there.
+func stackVarsAt(vars []stackVar, off int64, lo, hi uint64) []PointerMapVar {
+	in := func(v *stackVar) bool {
+		return off >= v.fp && off < v.fp+v.size && v.hi > lo && v.lo < hi
+	}
+	outer := false
+	for i := range vars {
+		if !vars[i].inlined && in(&vars[i]) {
+			outer = true
+			break
+		}
+	}
+	var pv []PointerMapVar
+next:
+	for i := range vars {
+		v := &vars[i]
+		if !in(v) || outer && v.inlined {
+			continue
+		}
+		p := PointerMapVar{Name: v.name, Type: v.typ, Off: v.off + off - v.fp}
+		for _, q := range pv {
+			if q.Name == p.Name && q.Off == p.Off {
+				continue next
+			}
+		}
+		pv = append(pv, p)
+	}
+	return pv
+}
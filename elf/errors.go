@@ -0,0 +1,98 @@
+package elf
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrNotGoBinary is returned by Open for files that are not ELF, PE or
+// Mach-O files or do not contain the runtime data of a Go binary.
+var ErrNotGoBinary = errors.New("not a Go binary")
+
+// ErrMalformed is returned when the runtime data of the binary is
+// inconsistent, e.g. truncated or pointing outside of the file.
+var ErrMalformed = errors.New("malformed Go runtime data")
+
+// ErrNoDWARF is returned when the binary has no DWARF, e.g. built with
+// -ldflags=-w, for what needs the layout of the runtime data structures.
+var ErrNoDWARF = errors.New("no DWARF in the binary, needed for the layout of the runtime data structures")
+
+// UnsupportedVersionError is returned by Open for binaries built with a Go
+// release which runtime data layout is not known.
+type UnsupportedVersionError struct {
+	Magic  uint32 // the pclntab magic of the binary
+	Reason string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported Go version (pclntab magic %#x): %s", e.Magic, e.Reason)
+}
+
+// SectionNotFoundError is returned when data the runtime refers to is not
+// present in the file.
+type SectionNotFoundError struct {
+	Name string // section or runtime table looked for
+	Addr uint64 // its address, 0 if not known
+}
+
+func (e *SectionNotFoundError) Error() string {
+	if e.Addr != 0 {
+		return fmt.Sprintf("section not found: %s at %#x", e.Name, e.Addr)
+	}
+	return "section not found: " + e.Name
+}
+
+// FuncNotFoundError is returned when a function is looked up by a name not
+// in the binary.
+type FuncNotFoundError struct {
+	Name string
+}
+
+func (e *FuncNotFoundError) Error() string {
+	return "function not found: " + e.Name
+}
+
+// TypeNotFoundError is returned when a type is looked up by a name not in
+// the binary.
+type TypeNotFoundError struct {
+	Name string
+}
+
+func (e *TypeNotFoundError) Error() string {
+	return "type not found: " + e.Name
+}
+
+// PCNotFoundError is returned when an address is looked up which is not in
+// the text of any function.
+type PCNotFoundError struct {
+	PC uint64
+}
+
+func (e *PCNotFoundError) Error() string {
+	return fmt.Sprintf("no function at pc %#x", e.PC)
+}
+
+// decodeError carries an error from deep inside the decoding to the public
+// entry point, which returns it through catch.
+type decodeError struct {
+	err error
+}
+
+func fail(err error) {
+	panic(decodeError{err})
+}
+
+// catch recovers from a failed decoding and stores the error in *err. Out of
+// range accesses caused by inconsistent data are reported as ErrMalformed.
+func catch(err *error) {
+	switch r := recover().(type) {
+	case nil:
+	case decodeError:
+		*err = r.err
+	case runtime.Error:
+		*err = fmt.Errorf("%w: %v", ErrMalformed, r)
+	default:
+		panic(r)
+	}
+}
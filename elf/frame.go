@@ -0,0 +1,240 @@
+package elf
+
+import "io"
+
+// _FUNCDATA_ArgInfo encoding, see runtime/traceback.go:printArgs.
+const (
+	argInfoEndSeq         = 0xff
+	argInfoStartAgg       = 0xfe
+	argInfoEndAgg         = 0xfd
+	argInfoDotdotdot      = 0xfc
+	argInfoOffsetTooLarge = 0xfb
+	argInfoMaxLen         = (10*3 + 2) * 2 // nested aggregates are at most 10 deep
+)
+
+// argInfoSlot is an argument word, or part of one, described by
+// _FUNCDATA_ArgInfo, at off from argp.
+type argInfoSlot struct {
+	off, size uint8
+	live      bool
+}
+
+// argSlots decodes the _FUNCDATA_ArgInfo of f, telling whether each slot is
+// live at pc from its _FUNCDATA_ArgLiveInfo, the way the runtime prints the
+// arguments in tracebacks.
+func (e *ELF_Info) argSlots(f *_func, pc uint64) []argInfoSlot {
+	info := e.funcdata(f, _FUNCDATA_ArgInfo)
+	if info == 0 {
+		return nil
+	}
+	p := e.mem(info)
+	if len(p) > argInfoMaxLen {
+		p = p[:argInfoMaxLen]
+	}
+	// the slots from startOffset on are live if their bit is set in the
+	// liveness bitmap at pc, the others always are
+	startOffset := uint8(0xff)
+	var liveBits []byte
+	if live := e.funcdata(f, _FUNCDATA_ArgLiveInfo); live != 0 {
+		startOffset = e.read(live, 1)[0]
+		if idx, ok := e.pcvalueAt(f, e.pcdata(f, _PCDATA_ArgLiveIndex), pc); ok && idx > 0 {
+			liveBits = e.mem(live + uint64(idx))
+		}
+	}
+	var slots []argInfoSlot
+	slotIdx := 0
+	for i := 0; i < len(p); i++ {
+		switch o := p[i]; o {
+		case argInfoEndSeq:
+			return slots
+		case argInfoStartAgg, argInfoEndAgg, argInfoDotdotdot, argInfoOffsetTooLarge:
+		default:
+			if i+1 >= len(p) {
+				return slots
+			}
+			i++
+			s := argInfoSlot{off: o, size: p[i], live: true}
+			if o >= startOffset {
+				if liveBits != nil && slotIdx/8 < len(liveBits) {
+					s.live = liveBits[slotIdx/8]&(1<<(slotIdx%8)) != 0
+				}
+				slotIdx++
+			}
+			slots = append(slots, s)
+		}
+	}
+	return slots
+}
+
+// stackmapBit reports whether bit i of the bit vector of index idx of m is
+// set.
+func stackmapBit(m *stackmap, idx, i int) bool {
+	n := int(m.nbit+7) / 8
+	return m.bytedata[idx*n+i/8]&(1<<(i%8)) != 0
+}
+
+// frameGeometry is where the parts of a frame are, from its sp: the
+// arguments start at argp, the locals end at varp and the frame of the
+// caller starts at fp.
+type frameGeometry struct {
+	fp, varp, argp int64
+	savedFP        bool // the frame pointer of the caller is saved at varp
+}
+
+// frameGeometry returns the geometry of a frame of the given size, see
+// runtime unwinder.resolveInternal.
+func (e *ELF_Info) frameGeometry(size int) frameGeometry {
+	w := int64(e.dec.ptrSize)
+	usesLR := e.usesLR()
+	minSize, _ := e.minFrameSize()
+	var g frameGeometry
+	g.fp = int64(size)
+	if !usesLR {
+		g.fp += w
+	}
+	g.varp = g.fp
+	if !usesLR {
+		g.varp -= w
+	}
+	if g.varp > 0 && e.framePointers() {
+		g.varp -= w
+		g.savedFP = true
+	}
+	g.argp = g.fp + int64(minSize)
+	return g
+}
+
+// framePointers reports whether frames save the frame pointer of the
+// caller, see runtime.framepointer_enabled.
+func (e *ELF_Info) framePointers() bool {
+	a := e.obj.arch()
+	return a == "amd64" || a == "arm64"
+}
+
+func (e *ELF_Info) PrintFrame(out io.Writer, fn string, pc uint64) error {
+	l, err := e.FrameLayout(fn, pc)
+	return writeText(out, l, err)
+}
+
+// FrameLayout returns the frame of the function fn at pc, word by word, as
+// the garbage collector sees it when pc is the return address of a call in
+// fn, like the pcs of a traceback: the size of the frame comes from pcsp,
+// the live pointers from the argument and local pointer maps at pc and the
+// stack objects from their records. The words are addressed from sp, that
+// of fn at pc, and from fp, the sp of its caller.
+func (e *ELF_Info) FrameLayout(fn string, pc uint64) (l *FrameLayout, err error) {
+	defer catch(&err)
+	f, err := e.lookupFunc(fn)
+	if err != nil {
+		return nil, err
+	}
+	if pc < f.entry || pc >= e.funcEnd(f) {
+		return nil, &PCNotFoundError{pc}
+	}
+	w := int64(e.dec.ptrSize)
+	usesLR := e.usesLR()
+	minSize, align := e.minFrameSize()
+	size, _ := e.pcvalueAt(f, f.pcsp, pc)
+	l = &FrameLayout{Func: fn, File: e.func_file(f), PC: pc, Offset: pc - f.entry, Size: size, Slots: []FrameSlot{}}
+	fg := e.frameGeometry(size)
+	fp, varp, argp, savedFP := fg.fp, fg.varp, fg.argp, fg.savedFP
+
+	// see runtime stkframe.getStackMap
+	tracepc := pc
+	if pc != f.entry {
+		tracepc--
+		if v, ok := e.pcvalueAt(f, e.pcdata(f, _PCDATA_StackMapIndex), tracepc); ok && v >= 0 {
+			l.StackMap = v
+		}
+	}
+	var locals, args *stackmap
+	nlocals, nargs := int64(0), int64(0)
+	minLocals := int64(minSize)
+	if e.obj.arch() == "arm64" {
+		minLocals = int64(align)
+	}
+	if p := e.funcdata(f, _FUNCDATA_LocalsPointerMaps); p != 0 && varp > minLocals {
+		if m := e.stackmap(p); int32(l.StackMap) < m.n {
+			locals, nlocals = m, int64(m.nbit)
+		}
+	}
+	if f.args > 0 {
+		nargs = int64(f.args) / w
+	}
+	if p := e.funcdata(f, _FUNCDATA_ArgsPointerMaps); p != 0 {
+		if m := e.stackmap(p); int32(l.StackMap) < m.n {
+			args = m
+			if int64(m.nbit) > nargs {
+				nargs = int64(m.nbit)
+			}
+		}
+	}
+	slots := e.argSlots(f, tracepc)
+	for _, s := range slots {
+		if n := (int64(s.off) + int64(s.size) + w - 1) / w; n > nargs {
+			nargs = n
+		}
+	}
+
+	top := argp + nargs*w
+	bottom := int64(0)
+	if usesLR && savedFP && size > 0 {
+		// the frame pointer of the caller is saved below sp
+		bottom = -w
+	}
+	for off := top - w; off >= bottom; off -= w {
+		s := FrameSlot{SP: off, FP: off - fp}
+		switch {
+		case off >= argp:
+			s.Kind = "arg"
+			i := int((off - argp) / w)
+			s.Pointer = args != nil && i < int(args.nbit) && stackmapBit(args, l.StackMap, i)
+			for j, a := range slots {
+				if o := argp + int64(a.off); o < off+w && o+int64(a.size) > off {
+					s.Arg = &FrameArg{Index: j, Off: int(a.off), Size: int(a.size), Live: a.live}
+					break
+				}
+			}
+		case off >= fp:
+			s.Kind = "caller"
+		case !usesLR && off == fp-w:
+			s.Kind = "return address"
+		case !usesLR && savedFP && off == fp-2*w:
+			s.Kind = "frame pointer"
+		case usesLR && size > 0 && off == 0:
+			s.Kind = "return address"
+		case off < 0:
+			s.Kind = "frame pointer"
+		default:
+			s.Kind = "local"
+			if i := (off - (varp - nlocals*w)) / w; locals != nil && off >= varp-nlocals*w {
+				s.Pointer = stackmapBit(locals, l.StackMap, int(i))
+			}
+		}
+		l.Slots = append(l.Slots, s)
+	}
+	if p := e.funcdata(f, _FUNCDATA_StackObjects); p != 0 {
+		for _, o := range e.stackObjects(p) {
+			base := argp + int64(o.off)
+			if o.off < 0 {
+				base = varp + int64(o.off)
+			}
+			var gcbits []byte
+			if o._ptrdata > 0 {
+				gcbits = e.read(o.gcdata, (int(o._ptrdata)/int(w)+7)/8)
+			}
+			for i := range l.Slots {
+				s := &l.Slots[i]
+				if s.SP < base || s.SP >= base+int64(o.size) {
+					continue
+				}
+				off := o.off
+				s.Object = &off
+				if j := (s.SP - base) / w; j*w < int64(o._ptrdata) {
+					s.ObjectPointer = gcbits[j/8]&(1<<(j%8)) != 0
+				}
+			}
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,187 @@
+package elf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the pcdata tables of a function, see Func.PCData
+const (
+	PCDataUnsafePoint   = _PCDATA_UnsafePoint
+	PCDataStackMapIndex = _PCDATA_StackMapIndex
+	PCDataInlTreeIndex  = _PCDATA_InlTreeIndex
+	PCDataArgLiveIndex  = _PCDATA_ArgLiveIndex
+)
+
+// Func is a function of the binary, from its _func record.
+type Func struct {
+	Name      string `json:"name"`
+	Entry     uint64 `json:"entry"`
+	End       uint64 `json:"end"`       // the entry of the next function
+	Args      int32  `json:"args"`      // size of the arguments and results
+	FrameSize int    `json:"frameSize"` // largest sp offset, see PCSP
+	File      string `json:"file"`      // of the entry
+	StartLine int    `json:"startLine"` // of the func keyword, go1.20+
+	FuncID    uint8  `json:"funcID"`    // non-zero for special runtime functions
+	Flags     uint8  `json:"flags"`
+
+	e *ELF_Info
+	f *_func
+}
+
+// Func returns the function named name.
+func (e *ELF_Info) Func(name string) (fn *Func, err error) {
+	defer catch(&err)
+	f, err := e.lookupFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.newFunc(f), nil
+}
+
+// FuncForPC returns the function containing pc.
+func (e *ELF_Info) FuncForPC(pc uint64) (fn *Func, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	f := e.findFuncPC(pc)
+	if f == nil {
+		return nil, &PCNotFoundError{pc}
+	}
+	return e.newFunc(f), nil
+}
+
+func (e *ELF_Info) newFunc(f *_func) *Func {
+	fn := &Func{
+		Name:      e.getFuncName(f),
+		Entry:     f.entry,
+		End:       e.funcEnd(f),
+		Args:      f.args,
+		File:      e.func_file(f),
+		StartLine: int(f.startLine),
+		FuncID:    uint8(f.funcID),
+		Flags:     uint8(f.flag),
+		e:         e,
+		f:         f,
+	}
+	for _, v := range e.getpcvaluefunc(f, func(f *_func) uint32 { return f.pcsp }) {
+		if v.value > fn.FrameSize {
+			fn.FrameSize = v.value
+		}
+	}
+	return fn
+}
+
+// funcEnd returns the end of f, the entry of the function following it in
+// ftab.
+func (e *ELF_Info) funcEnd(f *_func) uint64 {
+	i := sort.Search(e.nfunc()+1, func(i int) bool {
+		entry, _ := e.ftab(i)
+		return entry > f.entry
+	})
+	if i > e.nfunc() {
+		return e.module.maxpc
+	}
+	entry, _ := e.ftab(i)
+	return entry
+}
+
+// PCSP returns the table of the offset of sp from its value at the entry.
+func (fn *Func) PCSP() *PCTable {
+	return fn.e.pcTable(fn.f, fn.f.pcsp)
+}
+
+// PCFile returns the table of the file number, see FileName.
+func (fn *Func) PCFile() *PCTable {
+	return fn.e.pcTable(fn.f, fn.f.pcfile)
+}
+
+// PCLN returns the table of the line number.
+func (fn *Func) PCLN() *PCTable {
+	return fn.e.pcTable(fn.f, fn.f.pcln)
+}
+
+// PCData returns the i-th pcdata table, e.g. PCDataStackMapIndex. The table
+// is empty if the function does not have it.
+func (fn *Func) PCData(i uint32) *PCTable {
+	return fn.e.pcTable(fn.f, fn.e.pcdata(fn.f, i))
+}
+
+// FileName returns the name of the file numbered fileno in the PCFile
+// table, "?" if there is no such file.
+func (fn *Func) FileName(fileno int) string {
+	return fn.e.fileName(fn.f, fileno)
+}
+
+// PCTable iterates over a pc-value table of a function, one range of pcs
+// with the same value at a time:
+//
+//	t := fn.PCSP()
+//	for t.Next() {
+//		fmt.Println(t.Start(), t.End(), t.Value())
+//	}
+//	if err := t.Err(); err != nil {
+//		...
+//	}
+type PCTable struct {
+	p          []byte // the rest of the table, nil at its end
+	quantum    uint32
+	first      bool
+	start, end uint64
+	value      int
+	err        error
+}
+
+// pcTable returns the table at offset off of pctab for f, an empty table if
+// off is 0.
+func (e *ELF_Info) pcTable(f *_func, off uint32) *PCTable {
+	t := &PCTable{quantum: uint32(e.header.minLC), first: true, end: f.entry, value: -1}
+	switch {
+	case off == 0:
+	case int(off) >= len(e.tab.pctab):
+		t.err = fmt.Errorf("%w: pc-value table at %#x past the end of pctab", ErrMalformed, off)
+	default:
+		t.p = e.tab.pctab[off:]
+	}
+	return t
+}
+
+// Next advances to the next range of pcs, it returns false at the end of
+// the table or if it is malformed, see Err.
+func (t *PCTable) Next() (ok bool) {
+	defer catch(&t.err)
+	if t.p == nil || t.err != nil {
+		return false
+	}
+	r, vd, pd := pc_next(t.p, t.first, t.quantum)
+	t.p = r
+	if r == nil {
+		return false
+	}
+	t.first = false
+	t.value += int(vd)
+	t.start, t.end = t.end, t.end+uint64(pd)
+	return true
+}
+
+// Start returns the first pc of the current range.
+func (t *PCTable) Start() uint64 {
+	return t.start
+}
+
+// End returns the pc following the current range.
+func (t *PCTable) End() uint64 {
+	return t.end
+}
+
+// Value returns the value in the current range.
+func (t *PCTable) Value() int {
+	return t.value
+}
+
+// Err returns the error that ended the iteration, nil at the end of the
+// table.
+func (t *PCTable) Err() error {
+	return t.err
+}
@@ -0,0 +1,302 @@
+package elf
+
+import (
+	"debug/dwarf"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Process is a running or dumped process of the binary.
+type Process struct {
+	Mem     io.ReaderAt // the memory of the process, at virtual addresses
+	Threads []Thread
+	Bias    uint64 // load address minus link address of the binary
+}
+
+// Thread is the state of a thread of a Process.
+type Thread struct {
+	ID         int    // the thread id, m.procid in the runtime
+	PC, SP, LR uint64 // LR is 0 on architectures without link register
+}
+
+// gStatusStrings are the names of the goroutine states, see runtime2.go.
+var gStatusStrings = []string{
+	"idle", "runnable", "running", "syscall", "waiting", "moribund", "dead",
+	"enqueue", "copystack", "preempted", "leaked",
+}
+
+const (
+	_Grunning = 2
+	_Gsyscall = 3
+	_Gwaiting = 4
+	_Gdead    = 6
+	_Gscan    = 0x1000
+)
+
+// dwarfField is a field of a runtime struct found in DWARF.
+type dwarfField struct {
+	off  uint64
+	size int64
+}
+
+// runtimeLayout is what Goroutines needs to know of the runtime data
+// structures, read from the DWARF of the binary as they change with every
+// release.
+type runtimeLayout struct {
+	fields            map[string]dwarfField // e.g. g.goid
+	allgs             uint64
+	waitReasonStrings uint64
+	nwaitReasons      int64
+}
+
+// runtimeFields are the fields of the runtime structs Goroutines reads,
+// those with a * being optional.
+var runtimeFields = map[string][]string{
+	"runtime.g":     {"stack", "sched", "atomicstatus", "goid", "waitreason", "m", "syscallsp", "syscallpc", "gopc", "*parentGoid"},
+	"runtime.gobuf": {"sp", "pc", "*lr"},
+	"runtime.stack": {"lo", "hi"},
+	"runtime.m":     {"procid"},
+}
+
+// runtimeLayout reads the layout of the runtime data structures from the
+// DWARF of the runtime package. runtime.allgs is taken from the symbol table
+// if there is one.
+func (e *ELF_Info) runtimeLayout() *runtimeLayout {
+	if len(e.obj.debugSection("info")) == 0 {
+		fail(ErrNoDWARF)
+	}
+	di, err := e.dwarf()
+	if err != nil {
+		fail(err)
+	}
+	d := di.d
+	l := &runtimeLayout{fields: map[string]dwarfField{}}
+	l.allgs, _ = e.obj.symbol("runtime.allgs")
+	r := d.Reader()
+	for {
+		ent, err := r.Next()
+		if err != nil {
+			fail(fmt.Errorf("reading DWARF: %w", err))
+		}
+		if ent == nil {
+			break
+		}
+		name, _ := ent.Val(dwarf.AttrName).(string)
+		switch ent.Tag {
+		case dwarf.TagCompileUnit:
+			if name != "runtime" {
+				r.SkipChildren()
+			}
+		case dwarf.TagStructType:
+			_, ok := runtimeFields[name]
+			if !ok {
+				break
+			}
+			t, err := di.typ(ent.Offset)
+			if err != nil {
+				fail(fmt.Errorf("reading DWARF: %w", err))
+			}
+			st, ok := t.(*dwarf.StructType)
+			if !ok {
+				break
+			}
+			for _, f := range st.Field {
+				l.fields[name[len("runtime."):]+"."+f.Name] = dwarfField{uint64(f.ByteOffset), f.Type.Size()}
+			}
+		case dwarf.TagVariable:
+			if name != "runtime.allgs" && name != "runtime.waitReasonStrings" {
+				break
+			}
+			loc, _ := ent.Val(dwarf.AttrLocation).([]byte)
+			if len(loc) != 1+e.dec.ptrSize || loc[0] != 0x03 { // DW_OP_addr
+				break
+			}
+			addr := e.dec.uintptr(loc[1:])
+			if name == "runtime.allgs" {
+				if l.allgs == 0 {
+					l.allgs = addr
+				}
+				break
+			}
+			l.waitReasonStrings = addr
+			off, _ := ent.Val(dwarf.AttrType).(dwarf.Offset)
+			if t, err := di.typ(off); err == nil {
+				if at, ok := t.(*dwarf.ArrayType); ok {
+					l.nwaitReasons = at.Count
+				}
+			}
+		}
+	}
+	for s, fields := range runtimeFields {
+		for _, f := range fields {
+			if f[0] == '*' {
+				continue
+			}
+			if _, ok := l.fields[s[len("runtime."):]+"."+f]; !ok {
+				fail(fmt.Errorf("%s.%s not found in DWARF", s, f))
+			}
+		}
+	}
+	if l.allgs == 0 {
+		fail(errors.New("runtime.allgs not found in the symbol table or DWARF"))
+	}
+	return l
+}
+
+// field reads the field name of the struct at addr, an integer of up to 8
+// bytes.
+func (l *runtimeLayout) field(m *memory, addr uint64, name string) uint64 {
+	f, ok := l.fields[name]
+	if !ok {
+		return 0
+	}
+	b := m.read(addr+f.off, int(f.size))
+	switch f.size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(m.e.dec.order.Uint16(b))
+	case 4:
+		return uint64(m.e.dec.uint32(b))
+	case 8:
+		return m.e.dec.order.Uint64(b)
+	}
+	fail(fmt.Errorf("%s has size %d", name, f.size))
+	return 0
+}
+
+// waitReason returns the name of wait reason r.
+func (l *runtimeLayout) waitReason(m *memory, r uint64) string {
+	if l.waitReasonStrings == 0 || r >= uint64(l.nwaitReasons) {
+		return fmt.Sprintf("waitreason %d", r)
+	}
+	return m.string(m.bias + l.waitReasonStrings + r*2*uint64(m.e.dec.ptrSize))
+}
+
+// PrintGoroutines prints the goroutines of p with their stacks, like the
+// runtime does in tracebacks.
+func (e *ELF_Info) PrintGoroutines(out io.Writer, p *Process) error {
+	gs, err := e.Goroutines(p)
+	return writeText(out, gs, err)
+}
+
+// Goroutines returns the goroutines of p, a process of the binary, with
+// their stacks. The goroutines are found in runtime.allgs, located with the
+// symbol table or the DWARF of the binary and decoded with the latter, so it
+// returns ErrNoDWARF if the binary has none. A goroutine which stack cannot
+// be fully walked has the frames up to the failure and Error set.
+func (e *ELF_Info) Goroutines(p *Process) (gs Goroutines, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	l := e.runtimeLayout()
+	m := &memory{e, p.Mem, p.Bias}
+	w := uint64(e.dec.ptrSize)
+	allgs := m.uintptr(l.allgs + p.Bias)
+	n := m.uintptr(l.allgs + p.Bias + w)
+	gs = Goroutines{}
+	for i := uint64(0); i < n; i++ {
+		g := m.uintptr(allgs + i*w)
+		if g == 0 {
+			continue
+		}
+		status := l.field(m, g, "g.atomicstatus") &^ _Gscan
+		if status == _Gdead {
+			continue
+		}
+		gs = append(gs, e.goroutine(m, l, p, g, status))
+	}
+	return gs, nil
+}
+
+// goroutine decodes the goroutine g in the given status.
+func (e *ELF_Info) goroutine(m *memory, l *runtimeLayout, p *Process, g, status uint64) Goroutine {
+	gr := Goroutine{ID: l.field(m, g, "g.goid"), Parent: l.field(m, g, "g.parentGoid")}
+	if status < uint64(len(gStatusStrings)) {
+		gr.Status = gStatusStrings[status]
+	} else {
+		gr.Status = fmt.Sprintf("status %d", status)
+	}
+	if status == _Gwaiting {
+		gr.WaitReason = l.waitReason(m, l.field(m, g, "g.waitreason"))
+	}
+	// goroutine 1 is created by the runtime before main
+	if gopc := l.field(m, g, "g.gopc"); gopc != 0 && gr.ID != 1 {
+		if f := e.findFuncPC(gopc - m.bias); f != nil {
+			gr.CreatedBy = &StackFrame{
+				PC:     gopc,
+				Func:   e.getFuncName(f),
+				Offset: gopc - m.bias - f.entry,
+				Frames: e.frames(f, gopc-m.bias-1),
+			}
+		}
+	}
+	sched := g + l.fields["g.sched"].off
+	pc, sp, lr := l.field(m, sched, "gobuf.pc"), l.field(m, sched, "gobuf.sp"), l.field(m, sched, "gobuf.lr")
+	switch {
+	case status == _Gsyscall && l.field(m, g, "g.syscallsp") != 0:
+		pc, sp, lr = l.field(m, g, "g.syscallpc"), l.field(m, g, "g.syscallsp"), 0
+	case status == _Grunning:
+		// the registers of its thread if it runs on its own stack, else it
+		// is on the system stack and sched is where it switched to it
+		mp := l.field(m, g, "g.m")
+		if mp == 0 {
+			break
+		}
+		gr.Thread = int(l.field(m, mp, "m.procid"))
+		stack := g + l.fields["g.stack"].off
+		lo, hi := l.field(m, stack, "stack.lo"), l.field(m, stack, "stack.hi")
+		for _, t := range p.Threads {
+			if t.ID != gr.Thread {
+				continue
+			}
+			if t.SP < lo || t.SP >= hi {
+				// in a signal handler, which interrupted the goroutine
+				if i, ok := e.interrupted(m, t); ok && i.SP >= lo && i.SP < hi {
+					t = i
+				}
+			}
+			if t.SP >= lo && t.SP < hi {
+				pc, sp, lr = t.PC, t.SP, t.LR
+			}
+		}
+	}
+	if sp == 0 {
+		gr.Error = "stack unavailable"
+		return gr
+	}
+	var err error
+	if gr.Stack, err = e.unwind(m, pc, sp, lr); err != nil {
+		gr.Error = err.Error()
+	}
+	return gr
+}
+
+// interrupted returns the state of thread t where the signal it is
+// handling interrupted it, from the signal frame the kernel pushed on the
+// signal stack below runtime.sigtramp, see rt_sigframe in Linux.
+func (e *ELF_Info) interrupted(m *memory, t Thread) (Thread, bool) {
+	frames, _ := e.unwind(m, t.PC, t.SP, t.LR)
+	if len(frames) == 0 || frames[len(frames)-1].Func != "runtime.sigtramp" {
+		return t, false
+	}
+	// the ucontext follows the return address on amd64, the siginfo on arm64
+	uc := frames[len(frames)-1].FP
+	switch e.obj.arch() {
+	case "amd64":
+		// uc_flags, uc_link, uc_stack, then r8-r15, rdi, rsi, rbp, rbx,
+		// rdx, rax, rcx, rsp, rip
+		regs := uc + 40
+		t.SP, t.PC, t.LR = m.uintptr(regs+15*8), m.uintptr(regs+16*8), 0
+	case "arm64":
+		// uc_flags, uc_link, uc_stack, uc_sigmask padded to 128 bytes, then
+		// fault_address, x0-x30, sp, pc
+		regs := uc + 128 + 176 + 8
+		t.LR, t.SP, t.PC = m.uintptr(regs+30*8), m.uintptr(regs+31*8), m.uintptr(regs+32*8)
+	default:
+		return t, false
+	}
+	return t, true
+}
@@ -0,0 +1,57 @@
+package elf
+
+import (
+	"sort"
+	"strings"
+)
+
+// funcIndex indexes the function table by name. Lookups by pc need no
+// index, see findFuncPC.
+type funcIndex struct {
+	names  []string       // in the order of ftab
+	byName map[string]int // ftab index of the first function with a name
+	sorted []int          // ftab indexes sorted by name
+}
+
+// funcIndex returns the index of the function table, building it on first
+// use. The pclntab must be loaded.
+func (e *ELF_Info) funcIndex() *funcIndex {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.index != nil {
+		return e.index
+	}
+	n := e.nfunc()
+	x := &funcIndex{names: make([]string, n), byName: make(map[string]int, n), sorted: make([]int, n)}
+	for i := range x.names {
+		_, off := e.ftab(i)
+		x.names[i] = e.getFuncName(e.funcAt(off))
+		if _, ok := x.byName[x.names[i]]; !ok {
+			x.byName[x.names[i]] = i
+		}
+		x.sorted[i] = i
+	}
+	sort.SliceStable(x.sorted, func(i, j int) bool {
+		return x.names[x.sorted[i]] < x.names[x.sorted[j]]
+	})
+	e.index = x
+	return x
+}
+
+// FuncsWithPrefix returns the functions which name starts with prefix,
+// sorted by name.
+func (e *ELF_Info) FuncsWithPrefix(prefix string) (fns []*Func, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	x := e.funcIndex()
+	i := sort.Search(len(x.sorted), func(i int) bool {
+		return x.names[x.sorted[i]] >= prefix
+	})
+	for ; i < len(x.sorted) && strings.HasPrefix(x.names[x.sorted[i]], prefix); i++ {
+		_, off := e.ftab(x.sorted[i])
+		fns = append(fns, e.newFunc(e.funcAt(off)))
+	}
+	return fns, nil
+}
@@ -0,0 +1,98 @@
+package elf
+
+import "io"
+
+// itabs returns the addresses of the itabs built by the linker, from
+// itablinks or, as of go1.27, by walking them in the types section.
+func (e *ELF_Info) itabs() ([]uint64, error) {
+	m := e.module
+	w := e.dec.ptrSize
+	var itabs []uint64
+	if e.ver >= ver127 {
+		for p, end := m.types+m.itaboffset, m.types+m.itaboffset+m.itabsize; p < end; {
+			it := e.decodeItab(p)
+			itabs = append(itabs, p)
+			p = align(p+uint64(2*w+4+len(it.fun)*w), w)
+		}
+		return itabs, nil
+	}
+	b := e.mem(m.itablinks.data)
+	if uint64(len(b)) < m.itablinks.len*uint64(w) {
+		return nil, &SectionNotFoundError{"itablinks", m.itablinks.data}
+	}
+	for i := 0; i < int(m.itablinks.len); i++ {
+		itabs = append(itabs, e.dec.uintptr(b[i*w:]))
+	}
+	return itabs, nil
+}
+
+// decodeItab decodes the itab at p, with as many functions as its interface
+// has methods.
+func (e *ELF_Info) decodeItab(p uint64) *itab {
+	w := e.dec.ptrSize
+	b := e.read(p, 4*w)
+	it := &itab{addr: p, inter: e.dec.uintptr(b), typ: e.dec.uintptr(b[w:]), hash: e.dec.uint32(b[2*w:])}
+	fun := 2*w + 4
+	// up to go1.21 hash is followed by 4 bytes of padding, which matters on
+	// 32-bit targets only. go1.21 shares the layout of later releases, if
+	// the build info does not tell, a zero word after hash is the padding.
+	if w == 4 && e.ver <= ver121 {
+		switch {
+		case e.ver < ver121, e.release == 21, e.release == 0 && e.dec.uint32(b[12:]) == 0:
+			fun += 4
+		}
+	}
+	fun = int(align(uint64(fun), w))
+	n := len(e.decodeType(it.inter).iface.methods)
+	f := e.read(p+uint64(fun), w)
+	if e.dec.uintptr(f) == 0 {
+		it.fun = []uint64{0}
+		return it
+	}
+	f = e.read(p+uint64(fun), n*w)
+	for i := 0; i < n; i++ {
+		it.fun = append(it.fun, e.dec.uintptr(f[i*w:]))
+	}
+	return it
+}
+
+// PrintItabs prints the interface tables as concrete type -> interface type,
+// with the functions implementing the methods of the interface. Only the
+// itabs of the interface named iface and of the concrete type named typ are
+// printed if they are not empty.
+func (e *ELF_Info) PrintItabs(out io.Writer, iface, typ string) error {
+	itabs, err := e.Itabs(iface, typ)
+	return writeText(out, itabs, err)
+}
+
+// Itabs returns the interface tables, only those of the interface named
+// iface and of the concrete type named typ if they are not empty.
+func (e *ELF_Info) Itabs(iface, typ string) (itabs Itabs, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	addrs, err := e.itabs()
+	if err != nil {
+		return nil, err
+	}
+	itabs = Itabs{}
+	for _, p := range addrs {
+		it := e.decodeItab(p)
+		in, tn := e.typeRef(it.inter), e.typeRef(it.typ)
+		if iface != "" && in.Name != iface || typ != "" && tn.Name != typ {
+			continue
+		}
+		t := Itab{Addr: p, Type: tn, Interface: in}
+		methods := e.decodeType(it.inter).iface.methods
+		for i, fn := range it.fun {
+			name := "?"
+			if i < len(methods) {
+				name = e.name(e.module.types + uint64(methods[i].name))
+			}
+			t.Methods = append(t.Methods, ItabMethod{name, e.funcAddrRef(fn)})
+		}
+		itabs = append(itabs, t)
+	}
+	return itabs, nil
+}
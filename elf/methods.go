@@ -0,0 +1,87 @@
+package elf
+
+import "io"
+
+// textAddr resolves the text offset off, as found in methods, to an
+// address. ok is false if the linker eliminated the method (off is -1).
+func (e *ELF_Info) textAddr(off textOff) (addr uint64, ok bool) {
+	if off == -1 {
+		return 0, false
+	}
+	return e.module.text + uint64(uint32(off)), true
+}
+
+// funcRef returns a reference to the function at the text offset off, nil
+// if it was eliminated.
+func (e *ELF_Info) funcRef(off textOff) *FuncRef {
+	addr, ok := e.textAddr(off)
+	if !ok {
+		return nil
+	}
+	r := e.funcAddrRef(addr)
+	return &r
+}
+
+// funcAddrRef returns a reference to the function at addr, without a name
+// if no function starts there.
+func (e *ELF_Info) funcAddrRef(addr uint64) FuncRef {
+	if f := e.findFuncPC(addr); f != nil && f.entry == addr {
+		return FuncRef{addr, e.getFuncName(f)}
+	}
+	return FuncRef{Addr: addr}
+}
+
+// PrintMethods prints the package path and methods of the types with an
+// uncommontype, or only of the type named typ if it is not empty. The
+// interface (ifn) and direct call (tfn) implementations of each method are
+// resolved to functions, methods the linker found unreachable have them -1.
+func (e *ELF_Info) PrintMethods(out io.Writer, typ string) error {
+	sets, err := e.Methods(typ)
+	return writeText(out, sets, err)
+}
+
+// Methods returns the method sets of the types with an uncommontype, or
+// only of the type named typ if it is not empty.
+func (e *ELF_Info) Methods(typ string) (sets MethodSets, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	types, err := e.allTypes()
+	if err != nil {
+		return nil, err
+	}
+	sets = MethodSets{}
+	for _, t := range types {
+		name := e.typeName(t)
+		if typ != "" && name != typ {
+			continue
+		}
+		d := e.decodeType(t)
+		if d.uncommon == nil && typ == "" {
+			continue
+		}
+		s := MethodSet{Addr: t, Name: name, Methods: []Method{}}
+		if u := d.uncommon; u != nil {
+			s.Uncommon = true
+			s.PkgPath = e.name(e.module.types + uint64(u.pkgpath))
+			s.Exported = int(u.xcount)
+			for _, m := range u.methods {
+				mt := Method{
+					Name: e.name(e.module.types + uint64(m.name)),
+					IFn:  e.funcRef(m.ifn),
+					TFn:  e.funcRef(m.tfn),
+				}
+				if m.mtyp != -1 {
+					mt.Type = e.typeRefPtr(e.module.types + uint64(m.mtyp))
+				}
+				s.Methods = append(s.Methods, mt)
+			}
+		}
+		sets = append(sets, s)
+	}
+	if typ != "" && len(sets) == 0 {
+		return nil, &TypeNotFoundError{typ}
+	}
+	return sets, nil
+}
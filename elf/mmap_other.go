@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package elf
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile fails, the files are read with ReadAt on this system.
+func mmapFile(f *os.File) ([]byte, error) {
+	return nil, errors.New("mmap not supported")
+}
+
+func munmap(b []byte) error {
+	return nil
+}
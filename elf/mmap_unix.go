@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package elf
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the whole of f read-only in memory.
+func mmapFile(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if !fi.Mode().IsRegular() || size <= 0 || int64(int(size)) != size {
+		return nil, fmt.Errorf("cannot map %s of size %d", f.Name(), size)
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
@@ -1,342 +1,611 @@
 package elf
 
 import (
-	felf "debug/elf"
 	"fmt"
 	"io"
-	"os"
-	"reflect"
 	"sort"
-	"strconv"
 	"strings"
-	"unsafe"
+	"sync"
 )
 
 const (
-	SEC_PCLN      = ".gopclntab"
-	SEC_RODATA    = ".rodata"
-	SEC_TYPELINK  = ".typelink"
-	FIRST_MOD_SYM = "runtime.firstmoduledata"
+	SEC_PCLN       = ".gopclntab"
+	SEC_MACHO_PCLN = "__gopclntab"
+	SEC_RODATA     = ".rodata"
+	SEC_TYPELINK   = ".typelink"
+	FIRST_MOD_SYM  = "runtime.firstmoduledata"
+	PCLNTAB_SYM    = "runtime.pclntab"
 )
 
+// ELF_Info is a Go binary opened with Open. Its methods may be called from
+// several goroutines at once.
 type ELF_Info struct {
-	file           *felf.File
+	mu             sync.Mutex // guards the lazy loading of the tables
+	obj            objFile
+	secs           []*section // the sections loaded in memory
+	ver            goVersion
+	dec            decoder
+	header         *pcHeader
 	module         *moduledata
-	rodata         []byte
-	gofunc         *byte
+	tab            pclntab
+	types          []uint64 // addresses of the types in typelinks
+	index          *funcIndex
+	debug          *debugInfo // the DWARF of the binary, read on first use
+	debugErr       error      // why the DWARF could not be read
 	pclnLoaded     bool
-	rodataLoaded   bool
 	typelinkLoaded bool
+	buildVersion   string // Go version from the build info
+	modinfo        string // module information from the build info
+	release        int    // minor Go release from the build info, 0 if not known
+	buildInfoErr   error  // why the build info could not be read
+	stripped       bool   // the moduledata was found without the symbol table
 }
 
+// pclntab holds the tables referenced by moduledata.
+type pclntab struct {
+	funcnametab []byte
+	cutab       []byte
+	filetab     []byte
+	pctab       []byte
+	pclntable   []byte
+	ftab        []byte
+}
+
+// Close closes the file. The Func and PCTable values returned by e must not
+// be used afterwards.
 func (e *ELF_Info) Close() error {
-	return e.file.Close()
+	return e.obj.Close()
+}
+
+func (e *ELF_Info) PrintFuncs(out io.Writer) error {
+	files, err := e.FuncsByFile()
+	return writeText(out, files, err)
+}
+
+// FuncsByFile returns the functions grouped by the file they are defined in.
+func (e *ELF_Info) FuncsByFile() (files Files, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	files = Files{}
+	names := e.funcIndex().names
+	index := make(map[string]int)
+	for i := 0; i < e.nfunc(); i++ {
+		_, off := e.ftab(i)
+		f := e.funcAt(off)
+		file := e.func_file(f)
+		j, ok := index[file]
+		if !ok {
+			j = len(files)
+			index[file] = j
+			files = append(files, File{Name: file})
+		}
+		files[j].Funcs = append(files[j].Funcs, names[i])
+	}
+	return files, nil
 }
 
-func (e *ELF_Info) PrintFuncs(out io.Writer) {
-	e.loadpcln()
-	m := make(map[string][]string)
-	for _, f := range e.module.ftab {
-		f := (*_func)(unsafe.Pointer(&e.module.pclntable[f.funcoff]))
-		e.process_func(out, f, m)
+// nfunc returns the number of functions in ftab, not counting the
+// trailing sentinel entry.
+func (e *ELF_Info) nfunc() int {
+	return int(e.module.ftab.len) - 1
+}
+
+// ftab returns the entry pc and the pclntable offset of the i-th function.
+func (e *ELF_Info) ftab(i int) (entry uint64, funcoff uint64) {
+	if e.ver >= ver118 {
+		b := e.tab.ftab[i*8:]
+		return e.module.text + uint64(e.dec.uint32(b)), uint64(e.dec.uint32(b[4:]))
 	}
+	w := e.dec.ptrSize
+	b := e.tab.ftab[i*2*w:]
+	return e.dec.uintptr(b), e.dec.uintptr(b[w:])
+}
 
-	for k, v := range m {
-		fmt.Fprintln(out, k+":")
-		for _, fn := range v {
-			fmt.Fprintf(out, "    %s\n", fn)
-		}
+// funcAt decodes the _func record at offset off of pclntable.
+func (e *ELF_Info) funcAt(off uint64) *_func {
+	b := e.tab.pclntable[off:]
+	d := &e.dec
+	f := &_func{}
+	p := 0
+	if e.ver >= ver118 {
+		f.entry = e.module.text + uint64(d.uint32(b))
+		p += 4
+	} else {
+		f.entry = d.uintptr(b)
+		p += d.ptrSize
 	}
+	f.nameoff = int32(d.uint32(b[p:]))
+	f.args = int32(d.uint32(b[p+4:]))
+	f.deferreturn = d.uint32(b[p+8:])
+	f.pcsp = d.uint32(b[p+12:])
+	f.pcfile = d.uint32(b[p+16:])
+	f.pcln = d.uint32(b[p+20:])
+	f.npcdata = d.uint32(b[p+24:])
+	f.cuOffset = d.uint32(b[p+28:])
+	p += 32
+	if e.ver >= ver120 {
+		f.startLine = int32(d.uint32(b[p:]))
+		p += 4
+	}
+	f.funcID = funcID(b[p])
+	f.flag = funcFlag(b[p+1])
+	f.nfuncdata = b[p+3]
+	f.pcdata = int(off) + p + 4
+	return f
+}
+
+// lookupFunc loads the pclntab and finds the function named fn.
+func (e *ELF_Info) lookupFunc(fn string) (*_func, error) {
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	if f := e.findFunc(fn); f != nil {
+		return f, nil
+	}
+	return nil, &FuncNotFoundError{fn}
 }
 
 func (e *ELF_Info) findFunc(fn string) *_func {
-	for _, f := range e.module.ftab {
-		f := (*_func)(unsafe.Pointer(&e.module.pclntable[f.funcoff]))
-		fname := toString(e.module.funcnametab[f.nameoff:])
-		if fname == fn {
-			return f
-		}
+	i, ok := e.funcIndex().byName[fn]
+	if !ok {
+		return nil
 	}
-	return nil
+	_, off := e.ftab(i)
+	return e.funcAt(off)
 }
 
-func (e *ELF_Info) PrintPCLN(out io.Writer, fn string) {
-	e.printPCvalue(out, fn,
-		func(f *_func) uint32 {
-			return f.pcln
-		},
-		func(v int, f *_func) any {
-			return strconv.Itoa(v)
-		})
+func (e *ELF_Info) PrintPCLN(out io.Writer, fn string) error {
+	t, err := e.PCLN(fn)
+	return writeText(out, t, err)
 }
 
-func (e *ELF_Info) PrintPCSP(out io.Writer, fn string) {
-	e.printPCvalue(out, fn,
-		func(f *_func) uint32 {
-			return f.pcsp
-		},
-		func(v int, f *_func) any {
-			return v
-		})
+// PCLN returns the pc->line table of the function fn.
+func (e *ELF_Info) PCLN(fn string) (*PCValues, error) {
+	return e.pcValues(fn, "pcln", func(f *_func) uint32 {
+		return f.pcln
+	})
 }
 
-func (e *ELF_Info) PrintTypes(out io.Writer) {
-	e.loadrodata()
-	e.loadpcln()
-	e.loadTypeLinks()
-	sort.Slice(e.module.typelinks, func(i, j int) bool {
-		return e.module.typelinks[i] < e.module.typelinks[j]
+func (e *ELF_Info) PrintPCSP(out io.Writer, fn string) error {
+	t, err := e.PCSP(fn)
+	return writeText(out, t, err)
+}
+
+// PCSP returns the pc->sp offset table of the function fn.
+func (e *ELF_Info) PCSP(fn string) (*PCValues, error) {
+	return e.pcValues(fn, "pcsp", func(f *_func) uint32 {
+		return f.pcsp
 	})
-	for _, o := range e.module.typelinks {
-		t := (*_type)(unsafe.Pointer(&e.rodata[o]))
-		e.printType(out, t, o)
+}
+
+func (e *ELF_Info) PrintTypes(out io.Writer) error {
+	types, err := e.Types(false)
+	return writeText(out, types, err)
+}
+
+// Types returns the types listed by the linker, see loadTypeLinks, with the
+// contents of their descriptors if expand is set.
+func (e *ELF_Info) Types(expand bool) (types Types, err error) {
+	defer catch(&err)
+	if err := e.loadTypeLinks(); err != nil {
+		return nil, err
 	}
+	types = Types{}
+	for _, t := range e.types {
+		typ := Type{Addr: t, Name: e.typeName(t)}
+		if expand {
+			typ.Desc = e.typeDescOf(e.decodeType(t))
+		}
+		types = append(types, typ)
+	}
+	return types, nil
 }
 
-func (e *ELF_Info) printType(out io.Writer, t *_type, o int32) {
-	fmt.Fprintf(out, "%#x: %s\n", e.module.rodata+uintptr(o), e.typeName(t))
+// offset of _type.str, after size, ptrdata, hash, tflag, align, fieldAlign,
+// kind, equal and gcdata.
+func (e *ELF_Info) typeStrOff() int {
+	return 4*e.dec.ptrSize + 8
 }
 
-func (e *ELF_Info) typeName(t *_type) (s string) {
-	b := e.rodata[t.str:]
-	i, l := readvarint(b[1:])
-	if l == 0 {
-		return ""
+func (e *ELF_Info) typeName(t uint64) string {
+	b := e.read(t, e.typeStrOff()+4)
+	str := int32(e.dec.uint32(b[e.typeStrOff():]))
+	s := e.name(e.module.types + uint64(str))
+	// the string of a named type is shared with the pointer to it
+	if tflag(b[2*e.dec.ptrSize+4])&tflagExtraStar != 0 && len(s) > 0 {
+		s = s[1:]
 	}
-	hdr := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	hdr.Data = uintptr(unsafe.Pointer(&b[1+i]))
-	hdr.Len = int(l)
-	return
+	return s
 }
 
-func (e *ELF_Info) loadrodata() {
-	if e.rodataLoaded {
-		return
+// name decodes the name at address n, see the comment of reflect.name for
+// the encoding.
+func (e *ELF_Info) name(n uint64) string {
+	name, _, _ := e.decodeName(n)
+	return name
+}
+
+// decodeName decodes the name at address n with its tag and flags (see
+// nameExported etc.).
+func (e *ELF_Info) decodeName(n uint64) (name, tag string, flags uint8) {
+	b := e.mem(n)
+	if b == nil {
+		return "", "", 0
+	}
+	str := func(b []byte) (string, []byte) {
+		var i, l uint32
+		if e.ver >= ver117 {
+			i, l = readvarint(b)
+		} else {
+			i, l = 2, uint32(b[0])<<8|uint32(b[1])
+		}
+		return string(b[i : i+l]), b[i+l:]
 	}
-	s := e.file.Section(SEC_RODATA)
-	if s == nil {
-		panic("section not found: " + SEC_RODATA)
+	flags = b[0]
+	name, b = str(b[1:])
+	if flags&nameHasTag != 0 {
+		tag, _ = str(b)
 	}
-	e.rodata = make([]byte, s.Size)
-	n, err := s.ReadAt(e.rodata, 0)
-	if n < len(e.rodata) {
-		panic(err)
+	return name, tag, flags
+}
+
+// mem returns the contents of the binary at virtual address addr, up to the
+// end of the section containing it, or nil if addr is not backed by the file.
+func (e *ELF_Info) mem(addr uint64) []byte {
+	for _, s := range e.secs {
+		if addr >= s.addr && addr < s.addr+s.filesz {
+			return s.contents()[addr-s.addr:]
+		}
 	}
-	d := e.module.gofunc - e.module.rodata
-	e.gofunc = (*byte)(unsafe.Pointer(uintptr(unsafe.Pointer(&e.rodata[0])) + d))
-	e.rodataLoaded = true
+	return nil
 }
 
-func (e *ELF_Info) PrintLocalPointerMap(out io.Writer, fn string) {
-	e.printPointerMap(out, fn, _FUNCDATA_LocalsPointerMaps)
+// read returns the contents of the binary at addr like mem, failing the
+// decoding if there are less than n bytes.
+func (e *ELF_Info) read(addr uint64, n int) []byte {
+	b := e.mem(addr)
+	if len(b) < n {
+		fail(fmt.Errorf("%w: %d bytes at %#x not in file", ErrMalformed, n, addr))
+	}
+	return b
 }
 
-func (e *ELF_Info) PrintArgPointerMap(out io.Writer, fn string) {
-	e.printPointerMap(out, fn, _FUNCDATA_ArgsPointerMaps)
+// mapped reports whether addr falls into a section loaded in memory.
+func (e *ELF_Info) mapped(addr uint64) bool {
+	for _, s := range e.secs {
+		if addr >= s.addr && addr < s.addr+s.size {
+			return true
+		}
+	}
+	return false
 }
 
-func (e *ELF_Info) PrintStackObjs(out io.Writer, fn string) {
-	f, p, off := e.getFuncData(fn, _FUNCDATA_StackObjects)
-	e.printFuncNameAndFile(out, f, fn)
-	fmt.Fprintf(out, "%#x:\n", off)
+func (e *ELF_Info) PrintLocalPointerMap(out io.Writer, fn string) error {
+	m, err := e.LocalPointerMap(fn)
+	return writeText(out, m, err)
+}
 
-	if p != nil {
-		n := *(*uintptr)(p)
-		p = unsafe.Add(p, unsafe.Sizeof(n))
-		r0 := (*stackObjectRecord)((p))
-		objs := unsafe.Slice(r0, int(n))
-		for _, s := range objs {
-			e.printStackObj(out, s)
+// LocalPointerMap returns the pointer map of the locals of the function fn.
+func (e *ELF_Info) LocalPointerMap(fn string) (*PointerMap, error) {
+	return e.pointerMap(fn, _FUNCDATA_LocalsPointerMaps)
+}
+
+func (e *ELF_Info) PrintArgPointerMap(out io.Writer, fn string) error {
+	m, err := e.ArgPointerMap(fn)
+	return writeText(out, m, err)
+}
+
+// ArgPointerMap returns the pointer map of the arguments of the function fn.
+func (e *ELF_Info) ArgPointerMap(fn string) (*PointerMap, error) {
+	return e.pointerMap(fn, _FUNCDATA_ArgsPointerMaps)
+}
+
+func (e *ELF_Info) PrintStackObjs(out io.Writer, fn string) error {
+	s, err := e.StackObjects(fn)
+	return writeText(out, s, err)
+}
+
+// StackObjects returns the stack objects of the function fn, with their gc
+// bits.
+func (e *ELF_Info) StackObjects(fn string) (so *StackObjects, err error) {
+	defer catch(&err)
+	f, off, err := e.getFuncData(fn, _FUNCDATA_StackObjects)
+	if err != nil {
+		return nil, err
+	}
+	so = &StackObjects{Func: fn, File: e.func_file(f), Addr: off, Objects: []StackObject{}}
+	if off != 0 {
+		for _, s := range e.stackObjects(off) {
+			o := StackObject{Off: s.off, Size: s.size, PtrData: s._ptrdata, GCData: s.gcdata}
+			if s._ptrdata > 0 {
+				l := int(s._ptrdata) / e.dec.ptrSize
+				l = (l + 7) / 8
+				o.GCBits = bitmap(e.read(s.gcdata, l)[:l])
+			}
+			so.Objects = append(so.Objects, o)
 		}
 	}
-
+	return so, nil
+}
+
+// stackObjects decodes the stack object records at address p.
+func (e *ELF_Info) stackObjects(p uint64) []stackObjectRecord {
+	d := &e.dec
+	b := e.read(p, d.ptrSize)
+	n := int(d.uintptr(b))
+	b = b[d.ptrSize:]
+	objs := make([]stackObjectRecord, n)
+	for i := range objs {
+		s := &objs[i]
+		if e.ver >= ver118 {
+			r := b[i*16:]
+			s.off = int32(d.uint32(r))
+			s.size = int32(d.uint32(r[4:]))
+			s._ptrdata = int32(d.uint32(r[8:]))
+			s.gcdata = e.module.rodata + uint64(d.uint32(r[12:]))
+			continue
+		}
+		r := b[i*2*d.ptrSize:]
+		s.off = int32(d.uintptr(r))
+		t := e.read(d.uintptr(r[d.ptrSize:]), 4*d.ptrSize+8)
+		s.size = int32(d.uintptr(t))
+		s._ptrdata = int32(d.uintptr(t[d.ptrSize:]))
+		s.gcdata = d.uintptr(t[3*d.ptrSize+8:])
+	}
+	return objs
 }
 
-func (e *ELF_Info) printStackObj(out io.Writer, s stackObjectRecord) {
-	printObject(out, s, 1, 1)
-	// prints the gc bits as well
-	if s._ptrdata > 0 {
-		b := &e.rodata[s.gcdataoff]
-		l := s._ptrdata / 8
-		l = (l + 7) / 8
-		s := unsafe.Slice(b, l)
-		fmt.Fprint(out, "gcbits:")
-		printbitmap(out, s)
+func (e *ELF_Info) getFuncData(fn string, i uint8) (*_func, uint64, error) {
+	f, err := e.lookupFunc(fn)
+	if err != nil {
+		return nil, 0, err
 	}
+	return f, e.funcdata(f, i), nil
 }
 
-func (e *ELF_Info) getFuncData(fn string, i uint8) (*_func, unsafe.Pointer, uintptr) {
-	e.loadpcln()
-	f := e.findFunc(fn)
-	if f == nil {
-		fmt.Fprintln(os.Stderr, "function not found: "+fn)
-		os.Exit(1)
+func (e *ELF_Info) pointerMap(fn string, i uint8) (pm *PointerMap, err error) {
+	defer catch(&err)
+	f, off, err := e.getFuncData(fn, i)
+	if err != nil {
+		return nil, err
 	}
-	p, off := e.funcdata(f, i)
-	return f, p, off
+	pm = &PointerMap{Func: fn, File: e.func_file(f), Addr: off, Ranges: []PointerMapRange{}}
+	if off != 0 {
+		pm.Ranges = e.stackmapRanges(e.stackmap(off), f)
+		e.nameBits(pm, f, i == _FUNCDATA_LocalsPointerMaps)
+	}
+	return pm, nil
 }
 
-func (e *ELF_Info) printPointerMap(out io.Writer, fn string, i uint8) {
-	f, p, off := e.getFuncData(fn, i)
-	e.printFuncNameAndFile(out, f, fn)
-	fmt.Fprintf(out, "%#x:\n", off)
-	m := (*stackmap)(p)
-	if m != nil {
-		e.printstackmap(out, m, f)
+// nameBits names the set bits of the ranges of pm, the local pointer map of
+// f if locals is set, else its argument pointer map, with the variables of
+// f the DWARF of the binary places in their words.
+func (e *ELF_Info) nameBits(pm *PointerMap, f *_func, locals bool) {
+	vars := e.frameVars(f)
+	if len(vars) == 0 {
+		return
 	}
+	w := int64(e.dec.ptrSize)
+	m := e.stackmap(pm.Addr)
+	for i := range pm.Ranges {
+		r := &pm.Ranges[i]
+		// the locals end at varp, the arguments start at argp, in the frame
+		// as it is at the calls of the range
+		size, _ := e.pcvalueAt(f, f.pcsp, r.Start)
+		g := e.frameGeometry(size)
+		for bit := 0; bit < int(m.nbit); bit++ {
+			if !stackmapBit(m, r.Index, bit) {
+				continue
+			}
+			off := g.argp - g.fp + int64(bit)*w
+			if locals {
+				off = g.varp - g.fp - int64(int(m.nbit)-bit)*w
+			}
+			for _, v := range stackVarsAt(vars, off, r.Start, r.End) {
+				v.Bit, v.FP = bit, off
+				r.Vars = append(r.Vars, v)
+			}
+		}
+	}
+}
 
+// stackmap decodes the stack map at address p.
+func (e *ELF_Info) stackmap(p uint64) *stackmap {
+	b := e.read(p, 8)
+	m := &stackmap{n: int32(e.dec.uint32(b)), nbit: int32(e.dec.uint32(b[4:]))}
+	m.bytedata = b[8 : 8+int(m.n)*int((m.nbit+7)/8)]
+	return m
 }
 
-func (e *ELF_Info) printstackmap(out io.Writer, m *stackmap, f *_func) {
+// stackmapRanges returns the bit vectors of m in effect in each range of
+// pcs of f.
+func (e *ELF_Info) stackmapRanges(m *stackmap, f *_func) []PointerMapRange {
+	ranges := []PointerMapRange{}
 	b := (m.nbit + 7) / 8
 	if b == 0 {
-		return
+		return ranges
 	}
 	pcv := e.getpcvaluefunc(f, func(f *_func) uint32 {
-		return *(*uint32)(unsafe.Pointer(uintptr(unsafe.Pointer(&f.nfuncdata)) + unsafe.Sizeof(f.nfuncdata) + _PCDATA_StackMapIndex*4))
+		return e.pcdata(f, _PCDATA_StackMapIndex)
 	})
 	for _, v := range pcv {
 		if v.value < 0 || int32(v.value) >= m.n {
 			continue
 		}
-		fmt.Fprintf(out, "    %#x-->%#x: ", v.pc_start, v.pc_end)
-		bytes := (*byte)(unsafe.Add(unsafe.Pointer(&m.bytedata[0]), v.value*int(b)))
-		printbitmap(out, unsafe.Slice(bytes, b))
+		ranges = append(ranges, PointerMapRange{
+			Start: v.pc_start,
+			End:   v.pc_end,
+			Index: v.value,
+			Bits:  bitmap(m.bytedata[v.value*int(b) : (v.value+1)*int(b)]),
+		})
 	}
+	return ranges
 }
 
-func printbitmap(out io.Writer, b []byte) {
+// bitmap formats the bytes of b in binary.
+func bitmap(b []byte) string {
 	s := make([]string, len(b))
 	for j := 0; j < len(b); j++ {
 		s[j] = fmt.Sprintf("%8.8b", b[j])
 	}
-	fmt.Fprintln(out, "  "+strings.Join(s, " "))
+	return strings.Join(s, " ")
 }
 
-func (e *ELF_Info) funcdata(f *_func, i uint8) (unsafe.Pointer, uintptr) {
-	e.loadrodata()
-	p := uintptr(unsafe.Pointer(&f.nfuncdata)) + unsafe.Sizeof(f.nfuncdata) + uintptr(f.npcdata)*4 + uintptr(i)*4
-	off := *(*uint32)(unsafe.Pointer(p))
-	if off == ^uint32(0) {
-		return unsafe.Pointer(uintptr(0)), 0
+// pcdata returns the pctab offset of the i-th pcdata table of f, 0 if f has
+// no such table.
+func (e *ELF_Info) pcdata(f *_func, i uint32) uint32 {
+	if i >= f.npcdata {
+		return 0
 	}
-	return unsafe.Pointer(uintptr(unsafe.Pointer(e.gofunc)) + uintptr(off)), e.module.gofunc + uintptr(off)
+	return e.dec.uint32(e.tab.pclntable[f.pcdata+int(i)*4:])
 }
 
-func (e *ELF_Info) printFuncNameAndFile(out io.Writer, f *_func, fn string) {
-	fmt.Fprintln(out, fn+"("+e.func_file(f)+"):")
+// funcdata returns the address of the i-th funcdata of f, 0 if f has none.
+func (e *ELF_Info) funcdata(f *_func, i uint8) uint64 {
+	if i >= f.nfuncdata {
+		return 0
+	}
+	p := f.pcdata + int(f.npcdata)*4
+	if e.ver >= ver118 {
+		off := e.dec.uint32(e.tab.pclntable[p+int(i)*4:])
+		if off == ^uint32(0) {
+			return 0
+		}
+		return e.module.gofunc + uint64(off)
+	}
+	// before go1.18 funcdata are pointers, aligned in memory
+	w := e.dec.ptrSize
+	p = int(align(e.module.pclntable.data+uint64(p), w) - e.module.pclntable.data)
+	return e.dec.uintptr(e.tab.pclntable[p+int(i)*w:])
 }
 
 type pcvalue struct {
-	pc_start uintptr // inclusive
-	pc_end   uintptr // exclusive
+	pc_start uint64 // inclusive
+	pc_end   uint64 // exclusive
 	value    int
 }
 
 func (e *ELF_Info) getpcvaluefunc(f *_func, of func(*_func) uint32) []pcvalue {
-	p := e.module.pctab[of(f):]
-	first := true
-	entry := e.module.text + uintptr(f.entryoff)
-	pcstart := entry
-	d := -1
+	off := of(f)
+	if off == 0 {
+		return nil
+	}
 	ret := []pcvalue{}
-	for r, vd, pd := pc_next(p, first); r != nil; r, vd, pd = pc_next(p, first) {
-		if first {
-			first = false
-		}
-		pc := pcstart + uintptr(pd)
-		d += int(vd)
-		ret = append(ret, pcvalue{pcstart, pc, d})
-		p = r
-		pcstart = pc
+	t := e.pcTable(f, off)
+	for t.Next() {
+		ret = append(ret, pcvalue{t.start, t.end, t.value})
+	}
+	if t.err != nil {
+		fail(t.err)
 	}
 	return ret
 }
 
-func (e *ELF_Info) getpcvalue(fn string, of func(*_func) uint32) (*_func, []pcvalue) {
-	e.loadpcln()
-	f := e.findFunc(fn)
-	if f == nil {
-		fmt.Printf("function '%s' not found", fn)
-		os.Exit(1)
+func (e *ELF_Info) getpcvalue(fn string, of func(*_func) uint32) (*_func, []pcvalue, error) {
+	f, err := e.lookupFunc(fn)
+	if err != nil {
+		return nil, nil, err
 	}
 	ret := e.getpcvaluefunc(f, of)
-	return f, ret
+	return f, ret, nil
 }
 
-func (e *ELF_Info) printPCvalue(out io.Writer, fn string, of func(*_func) uint32,
-	vm func(int, *_func) any) {
-	f, pcv := e.getpcvalue(fn, of)
-	e.printFuncNameAndFile(out, f, fn)
-
+// pcValues returns the pc-value table named table of the function fn, at
+// the offset of pctab returned by of.
+func (e *ELF_Info) pcValues(fn, table string, of func(*_func) uint32) (t *PCValues, err error) {
+	defer catch(&err)
+	f, pcv, err := e.getpcvalue(fn, of)
+	if err != nil {
+		return nil, err
+	}
+	t = &PCValues{Func: fn, File: e.func_file(f), Table: table, Values: []PCValue{}}
 	for _, p := range pcv {
-		m := vm(p.value, f)
-		switch tv := m.(type) {
-		case uint8, uint16, uint32, uint64, int8, int16, int32, int64, int, uintptr:
-			fmt.Fprintf(out, "    %#x-->%#x: %#x\n", p.pc_start, p.pc_end, tv)
-		case string:
-			fmt.Fprintf(out, "    %#x-->%#x: %s\n", p.pc_start, p.pc_end, tv)
-		default:
-			fmt.Fprintf(out, "    %#x-->%#x: %v\n", p.pc_start, p.pc_end, tv)
+		v := PCValue{Start: p.pc_start, End: p.pc_end, Value: p.value}
+		if table == "unsafepoint" {
+			v.Name = unsafePoint(p.value)
 		}
+		t.Values = append(t.Values, v)
 	}
+	return t, nil
 }
 
-func (e *ELF_Info) PrintSafePoints(out io.Writer, fn string) {
-	e.printPCvalue(out, fn,
-		func(f *_func) uint32 {
-			return *(*uint32)(unsafe.Pointer(uintptr(unsafe.Pointer(&f.nfuncdata)) + unsafe.Sizeof(f.nfuncdata))) // + _PCDATA_UnsafePoint*4
-		},
-		func(v int, f *_func) any {
-			switch v {
-			case _PCDATA_UnsafePointSafe:
-				return "safe"
-			case _PCDATA_UnsafePointUnsafe:
-				return "unsafe"
-			case _PCDATA_Restart1:
-				return "restart1"
-			case _PCDATA_Restart2:
-				return "restart2"
-			case _PCDATA_RestartAtEntry:
-				return "restartAtEntry"
-			default:
-				return v
-			}
-		})
+func (e *ELF_Info) PrintSafePoints(out io.Writer, fn string) error {
+	t, err := e.SafePoints(fn)
+	return writeText(out, t, err)
 }
 
-func (e *ELF_Info) PrintModule(out io.Writer) {
-	printModule(out, e.module)
+// SafePoints returns the unsafe point table of the function fn, telling
+// where it can be preempted asynchronously.
+func (e *ELF_Info) SafePoints(fn string) (*PCValues, error) {
+	return e.pcValues(fn, "unsafepoint", func(f *_func) uint32 {
+		return e.pcdata(f, _PCDATA_UnsafePoint)
+	})
 }
 
-func (e *ELF_Info) getFuncName(f *_func) string {
-	return toString(e.module.funcnametab[f.nameoff:])
+// unsafePoint names the value v of the _PCDATA_UnsafePoint table, "" if it
+// has no name.
+func unsafePoint(v int) string {
+	switch v {
+	case _PCDATA_UnsafePointSafe:
+		return "safe"
+	case _PCDATA_UnsafePointUnsafe:
+		return "unsafe"
+	case _PCDATA_Restart1:
+		return "restart1"
+	case _PCDATA_Restart2:
+		return "restart2"
+	case _PCDATA_RestartAtEntry:
+		return "restartAtEntry"
+	default:
+		return ""
+	}
 }
 
-func (e *ELF_Info) process_func(out io.Writer, fn *_func, m map[string][]string) {
-	fname := e.getFuncName(fn)
-	file := e.func_file(fn)
-	if s, ok := m[file]; ok {
-		m[file] = append(s, fname)
-	} else {
-		m[file] = []string{fname}
+func (e *ELF_Info) PrintModule(out io.Writer) error {
+	m, err := e.ModuleData()
+	return writeText(out, m, err)
+}
+
+// ModuleData returns the fields of the moduledata.
+func (e *ELF_Info) ModuleData() (*ModuleData, error) {
+	m := &ModuleData{}
+	for _, f := range e.module.fields {
+		m.Fields = append(m.Fields, Field{f.name, f.value})
 	}
+	return m, nil
+}
+
+func (e *ELF_Info) getFuncName(f *_func) string {
+	return toString(e.tab.funcnametab[f.nameoff:])
 }
 
 func (e *ELF_Info) func_file(fn *_func) string {
-	_, fileno := readvarint(e.module.pctab[fn.pcfile:])
-	fileno = uint32(zigzag_decode(fileno)) - 1 // pc delta starts at -1
-	if fn.cuOffset != ^uint32(0) && int(fn.cuOffset+fileno) < len(e.module.cutab) {
-		if fileoff := e.module.cutab[fn.cuOffset+fileno]; fileoff != ^uint32(0) {
-			if int(fileoff) < len(e.module.filetab) {
-				return toString(e.module.filetab[fileoff:])
+	if fn.pcfile == 0 {
+		return "?"
+	}
+	_, fileno := readvarint(e.tab.pctab[fn.pcfile:])
+	return e.fileName(fn, int(zigzag_decode(fileno))-1) // pc delta starts at -1
+}
+
+// fileName returns the name of the file numbered fileno in the compilation
+// unit of fn.
+func (e *ELF_Info) fileName(fn *_func, fileno int) string {
+	i := int(fn.cuOffset) + fileno
+	if fn.cuOffset != ^uint32(0) && fileno >= 0 && i < len(e.tab.cutab)/4 {
+		if fileoff := e.dec.uint32(e.tab.cutab[i*4:]); fileoff != ^uint32(0) {
+			if int(fileoff) < len(e.tab.filetab) {
+				return toString(e.tab.filetab[fileoff:])
 			}
 		}
 	}
 	return "?"
 }
 
-func pc_next(p []byte, first bool) (r []byte, vdelta int32, pcdelta int32) {
+// pc_next decodes the next value and pc delta of a pc-value table, pc deltas
+// are encoded in units of the instruction size quantum.
+func pc_next(p []byte, first bool, quantum uint32) (r []byte, vdelta int32, pcdelta int32) {
 	n, v := readvarint(p)
 	vdelta = zigzag_decode(v)
 	if vdelta == 0 && !first {
@@ -344,7 +613,7 @@ func pc_next(p []byte, first bool) (r []byte, vdelta int32, pcdelta int32) {
 	}
 	p = p[n:]
 	n, pd := readvarint(p)
-	pcdelta = int32(pd)
+	pcdelta = int32(pd * quantum)
 	r = p[n:]
 	return
 }
@@ -368,16 +637,11 @@ func zigzag_decode(d uint32) int32 {
 }
 
 func toString(fnames []byte) string {
-	type str struct {
-		p unsafe.Pointer
-		l uint64
-	}
 	l := indexByte(fnames, 0)
 	if l < 0 {
 		l = 0
 	}
-	s := unsafe.Pointer(&str{unsafe.Pointer(&fnames[0]), uint64(l)})
-	return *(*string)(s)
+	return string(fnames[:l])
 }
 
 func indexByte(bytes []byte, b byte) int {
@@ -389,86 +653,353 @@ func indexByte(bytes []byte, b byte) int {
 	return -1
 }
 
-func Open(elf string) *ELF_Info {
-	f, e := felf.Open(elf)
-	if e != nil {
-		panic(e)
-	}
+// Open opens the named ELF, PE or Mach-O file and locates the runtime data of
+// the Go binary in it. The file is mapped read-only in memory where the
+// system allows it, its sections are decoded on demand.
+func Open(name string) (*ELF_Info, error) {
+	return openFile(name, true)
+}
 
-	syms, e := f.Symbols()
-	if e != nil {
-		panic(e)
+func openFile(name string, mmap bool) (*ELF_Info, error) {
+	f, err := openObjFile(name, mmap)
+	if err != nil {
+		return nil, err
 	}
-	var fms *felf.Symbol
-	for _, sym := range syms {
-		if sym.Name == FIRST_MOD_SYM {
-			fms = &sym
-			break
-		}
+	ei, err := newInfo(f)
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
-	if fms == nil || fms.Section <= 0 {
-		panic("symbol not found: " + FIRST_MOD_SYM)
+	return ei, nil
+}
+
+// OpenReader opens the binary made of the size bytes of r, e.g. a member of
+// an archive. r must stay readable until Close, which does not close it.
+func OpenReader(r io.ReaderAt, size int64) (*ELF_Info, error) {
+	f, err := newObjFile(io.NewSectionReader(r, 0, size), &source{})
+	if err != nil {
+		return nil, err
 	}
-	var gosec *felf.Section = f.Sections[fms.Section]
-	off := fms.Value - gosec.Addr
+	ei, err := newInfo(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return ei, nil
+}
 
-	mds := unsafe.Sizeof(moduledata{})
-	var b = make([]byte, mds)
-	n, e := gosec.ReadAt(b, int64(off))
-	if n < len(b) {
-		panic(e)
+func newInfo(f objFile) (ei *ELF_Info, err error) {
+	defer catch(&err)
+	ei = &ELF_Info{obj: f, dec: f.decoder(), secs: f.sections()}
+	// the Go version helps picking the layout, binaries may lack it
+	ei.buildInfoErr = ei.loadBuildInfo()
+	if addr, ok := f.symbol(FIRST_MOD_SYM); ok {
+		err = ei.loadModule(addr)
+	} else {
+		ei.stripped = true
+		err = ei.loadStripped()
+	}
+	if err != nil {
+		return nil, err
 	}
+	return ei, nil
+}
 
-	m := (*moduledata)(unsafe.Pointer(&b[0]))
-	ei := &ELF_Info{file: f, module: m}
-	return ei
+// loadModule decodes the pcHeader and the moduledata at addr, picking the
+// layout from the pclntab magic.
+func (e *ELF_Info) loadModule(addr uint64) error {
+	b := e.mem(addr)
+	if len(b) < e.dec.ptrSize {
+		return &SectionNotFoundError{"moduledata", addr}
+	}
+	hdr := e.dec.uintptr(b)
+	h := e.mem(hdr)
+	if len(h) < 8 {
+		return &SectionNotFoundError{"pcHeader", hdr}
+	}
+	magic := e.dec.uint32(h)
+	vers, err := layoutFor(magic)
+	if err != nil {
+		return err
+	}
+	if int(h[7]) != e.dec.ptrSize {
+		return fmt.Errorf("%w: pcHeader pointer size %d does not match the binary", ErrMalformed, h[7])
+	}
+	if q := h[6]; q != 1 && q != 2 && q != 4 {
+		return fmt.Errorf("%w: pcHeader instruction size quantum %d", ErrMalformed, q)
+	}
+	if e.release != 0 {
+		vers = forRelease(vers, e.release)
+	}
+	e.header = e.decodeHeader(h, vers[0])
+	for _, v := range vers {
+		m := e.decodeModule(b, v)
+		if m != nil && e.validModule(m, hdr, v) {
+			e.ver = v
+			e.module = m
+			if v == ver116 && (e.release >= 17 || e.release == 0 && e.varintNames()) {
+				e.ver = ver117
+			}
+			return nil
+		}
+	}
+	return &UnsupportedVersionError{magic, fmt.Sprintf("moduledata at %#x does not match any known layout", addr)}
+}
+
+func (e *ELF_Info) decodeHeader(b []byte, v goVersion) *pcHeader {
+	r := &structReader{decoder: &e.dec, b: b}
+	h := &pcHeader{}
+	h.magic = r.uint32(r.next(4, 4))
+	r.next(2, 1) // pad1, pad2
+	h.minLC = r.uint8("minLC")
+	h.ptrSize = r.uint8("ptrSize")
+	h.nfunc = int(r.word("nfunc"))
+	h.nfiles = uint(r.word("nfiles"))
+	if v >= ver118 {
+		h.textStart = r.word("textStart")
+	}
+	h.funcnameOffset = r.word("funcnameOffset")
+	h.cuOffset = r.word("cuOffset")
+	h.filetabOffset = r.word("filetabOffset")
+	h.pctabOffset = r.word("pctabOffset")
+	h.pclnOffset = r.word("pclnOffset")
+	return h
+}
+
+// decodeModule decodes b as a moduledata of layout v, nil if b is too short.
+func (e *ELF_Info) decodeModule(b []byte, v goVersion) *moduledata {
+	r := &structReader{decoder: &e.dec, b: b}
+	m := &moduledata{}
+	m.pcHeader = r.word("pcHeader")
+	m.funcnametab = r.slice("funcnametab")
+	m.cutab = r.slice("cutab")
+	m.filetab = r.slice("filetab")
+	m.pctab = r.slice("pctab")
+	m.pclntable = r.slice("pclntable")
+	m.ftab = r.slice("ftab")
+	m.findfunctab = r.word("findfunctab")
+	m.minpc, m.maxpc = r.word("minpc"), r.word("maxpc")
+
+	m.text, m.etext = r.word("text"), r.word("etext")
+	m.noptrdata, m.enoptrdata = r.word("noptrdata"), r.word("enoptrdata")
+	m.data, m.edata = r.word("data"), r.word("edata")
+	m.bss, m.ebss = r.word("bss"), r.word("ebss")
+	m.noptrbss, m.enoptrbss = r.word("noptrbss"), r.word("enoptrbss")
+	if v >= ver120 {
+		m.covctrs, m.ecovctrs = r.word("covctrs"), r.word("ecovctrs")
+	}
+	m.end, m.gcdata, m.gcbss = r.word("end"), r.word("gcdata"), r.word("gcbss")
+	m.types = r.word("types")
+	if v >= ver127 {
+		m.typedesclen = r.word("typedesclen")
+	}
+	m.etypes = r.word("etypes")
+	if v >= ver127 {
+		m.itaboffset, m.itabsize = r.word("itaboffset"), r.word("itabsize")
+	}
+	if v >= ver118 {
+		m.rodata = r.word("rodata")
+		m.gofunc = r.word("gofunc")
+	}
+	if v >= ver127 {
+		m.epclntab = r.word("epclntab")
+	}
+
+	m.textsectmap = r.slice("textsectmap")
+	if v < ver127 {
+		m.typelinks = r.slice("typelinks")
+		m.itablinks = r.slice("itablinks")
+	}
+	m.ptab = r.slice("ptab")
+	m.pluginpath = r.string("pluginpath")
+	m.pkghashes = r.slice("pkghashes")
+	if v >= ver121 {
+		m.inittasks = r.slice("inittasks")
+	}
+	m.modulename = r.string("modulename")
+	m.modulehashes = r.slice("modulehashes")
+	m.hasmain = r.uint8("hasmain")
+	if v >= ver121 {
+		m.bad = r.uint8("bad") != 0
+	}
+	m.gcdatamask = r.bitvector("gcdatamask")
+	m.gcbssmask = r.bitvector("gcbssmask")
+	m.typemap = r.word("typemap")
+	if v < ver121 {
+		m.bad = r.uint8("bad") != 0
+	}
+	m.next = r.word("next")
+	if r.short {
+		return nil
+	}
+	m.fields = r.fields
+	return m
+}
+
+// validModule reports whether m is consistent, i.e. it was decoded with the
+// layout matching the binary.
+func (e *ELF_Info) validModule(m *moduledata, hdr uint64, v goVersion) bool {
+	if m.pcHeader != hdr || m.ftab.len != uint64(e.header.nfunc)+1 || m.hasmain > 1 || m.bad {
+		return false
+	}
+	// the tables are where the header says, ftab is at the start of pclntable
+	h := e.header
+	if m.funcnametab.data != hdr+h.funcnameOffset || m.cutab.data != hdr+h.cuOffset ||
+		m.filetab.data != hdr+h.filetabOffset || m.pctab.data != hdr+h.pctabOffset ||
+		m.pclntable.data != hdr+h.pclnOffset || m.ftab.data != m.pclntable.data {
+		return false
+	}
+	ranges := [][2]uint64{
+		{m.minpc, m.maxpc}, {m.text, m.etext}, {m.noptrdata, m.enoptrdata}, {m.data, m.edata},
+		{m.bss, m.ebss}, {m.noptrbss, m.enoptrbss}, {m.covctrs, m.ecovctrs}, {m.types, m.etypes},
+	}
+	for _, r := range ranges {
+		if r[0] > r[1] {
+			return false
+		}
+	}
+	if m.typedesclen > m.etypes-m.types || m.itaboffset+m.itabsize > m.etypes-m.types {
+		return false
+	}
+	slices := []slice{
+		m.funcnametab, m.cutab, m.filetab, m.pctab, m.pclntable, m.ftab, m.textsectmap,
+		m.typelinks, m.itablinks, m.ptab, m.pluginpath, m.pkghashes, m.inittasks,
+		m.modulename, m.modulehashes,
+	}
+	for _, s := range slices {
+		if s.len > s.cap || s.len > 0 && !e.mapped(s.data) {
+			return false
+		}
+	}
+	// the first function starts at minpc
+	ftab := e.mem(m.ftab.data)
+	if len(ftab) < 2*e.dec.ptrSize {
+		return false
+	}
+	if v >= ver118 {
+		return m.text+uint64(e.dec.uint32(ftab)) == m.minpc
+	}
+	return e.dec.uintptr(ftab) == m.minpc
 }
 
-func (e *ELF_Info) loadTypeLinks() {
-	if e.typelinkLoaded {
-		return
+// varintNames reports whether the names of a go1.16 layout are varint
+// prefixed, i.e. the binary was built with go1.17. Type strings are never
+// empty so the high byte of a go1.16 length is the giveaway.
+func (e *ELF_Info) varintNames() bool {
+	m := e.module
+	b := e.mem(m.typelinks.data)
+	if m.typelinks.len == 0 || len(b) < 4 {
+		return false
 	}
-	tl := e.file.Section(SEC_TYPELINK)
-	if tl == nil {
-		panic("section not found: " + SEC_TYPELINK)
+	t := e.mem(m.types + uint64(int32(e.dec.uint32(b))))
+	if len(t) < e.typeStrOff()+4 {
+		return false
 	}
-	b := make([]byte, tl.Size)
-	n, err := tl.ReadAt(b, 0)
-	if n < len(b) {
-		panic(err)
+	str := int32(e.dec.uint32(t[e.typeStrOff():]))
+	n := e.mem(m.types + uint64(str))
+	return len(n) > 1 && n[1] != 0
+}
+
+func (e *ELF_Info) loadTypeLinks() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.typelinkLoaded {
+		return nil
+	}
+	m := e.module
+	if e.ver >= ver127 {
+		// the type descriptors are laid out one after another
+		for t, end := m.types+uint64(e.dec.ptrSize), m.types+m.typedesclen; t < end; {
+			t = align(t, e.dec.ptrSize)
+			e.types = append(e.types, t)
+			t += uint64(e.typeDescriptorSize(t))
+		}
+	} else {
+		b := e.mem(m.typelinks.data)
+		if uint64(len(b)) < m.typelinks.len*4 {
+			return &SectionNotFoundError{SEC_TYPELINK, m.typelinks.data}
+		}
+		for i := uint64(0); i < m.typelinks.len; i++ {
+			e.types = append(e.types, m.types+uint64(int32(e.dec.uint32(b[i*4:]))))
+		}
 	}
-	*(*uintptr)(unsafe.Pointer(&e.module.typelinks)) = uintptr(unsafe.Pointer(&b[0]))
+	sort.Slice(e.types, func(i, j int) bool {
+		return e.types[i] < e.types[j]
+	})
 	e.typelinkLoaded = true
+	return nil
 }
 
-func (e *ELF_Info) loadpcln() {
+// typeDescriptorSize returns the size of the type descriptor at t including
+// its kind specific part, uncommontype and methods, see
+// internal/abi.Type.DescriptorSize.
+func (e *ELF_Info) typeDescriptorSize(t uint64) int {
+	d := &e.dec
+	w := d.ptrSize
+	b := e.read(t, 4*w+16)
+	size := 4*w + 16
+	add := 0
+	tf := tflag(b[2*w+4])
+	switch kind(b[2*w+7] & kindMask) {
+	case kindArray:
+		size += 3 * w
+	case kindChan:
+		size += 2 * w
+	case kindFunc:
+		size += w
+		in, out := int(d.order.Uint16(b[size-w:])), int(d.order.Uint16(b[size-w+2:]))&(1<<15-1)
+		add = (in + out) * w
+	case kindInterface:
+		size += 4 * w
+		add = int(d.uintptr(b[size-2*w:])) * 8
+	case kindMap:
+		size += 11 * w // 10 words and the flags
+	case kindPtr, kindSlice:
+		size += w
+	case kindStruct:
+		size += 4 * w
+		add = int(d.uintptr(b[size-2*w:])) * 3 * w
+	}
+	if tf&tflagUncommon != 0 {
+		mcount := int(d.order.Uint16(b[size+4:]))
+		size += 16 + mcount*16
+	}
+	return size + add
+}
+
+func align(v uint64, a int) uint64 {
+	return (v + uint64(a) - 1) &^ (uint64(a) - 1)
+}
+
+func (e *ELF_Info) loadpcln() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if e.pclnLoaded {
-		return
+		return nil
+	}
+	m := e.module
+	var err error
+	table := func(name string, s slice, elem uint64) []byte {
+		b := e.mem(s.data)
+		if uint64(len(b)) < s.len*elem {
+			err = &SectionNotFoundError{SEC_PCLN + " " + name, s.data}
+			return nil
+		}
+		return b[:s.len*elem]
 	}
-	pcln := e.file.Section(SEC_PCLN)
-	if pcln == nil {
-		panic("section not found: " + SEC_PCLN)
+	ftab := uint64(8)
+	if e.ver < ver118 {
+		ftab = 2 * uint64(e.dec.ptrSize)
 	}
-	b := make([]byte, pcln.Size)
-	n, err := pcln.ReadAt(b, 0)
-	if n < len(b) {
-		panic(err)
+	e.tab.funcnametab = table("funcnametab", m.funcnametab, 1)
+	e.tab.cutab = table("cutab", m.cutab, 4)
+	e.tab.filetab = table("filetab", m.filetab, 1)
+	e.tab.pctab = table("pctab", m.pctab, 1)
+	e.tab.pclntable = table("pclntable", m.pclntable, 1)
+	e.tab.ftab = table("ftab", m.ftab, ftab)
+	if err != nil {
+		return err
 	}
-	delta := int64(uintptr(unsafe.Pointer(&b[0]))) - int64(uintptr(unsafe.Pointer(e.module.pcHeader)))
-	relocate_module(e.module, delta)
 	e.pclnLoaded = true
-}
-
-func relocate_module(m *moduledata, d int64) {
-	relocate(unsafe.Pointer(&m.pcHeader), d)
-	relocate(unsafe.Pointer(&m.funcnametab), d)
-	relocate(unsafe.Pointer(&m.cutab), d)
-	relocate(unsafe.Pointer(&m.filetab), d)
-	relocate(unsafe.Pointer(&m.pctab), d)
-	relocate(unsafe.Pointer(&m.pclntable), d)
-	relocate(unsafe.Pointer(&m.ftab), d)
-}
-
-func relocate(p unsafe.Pointer, delta int64) {
-	*(*uintptr)(p) = uintptr(*(*int64)(p) + delta)
+	return nil
 }
@@ -1,30 +1,1149 @@
 package elf
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 )
 
-func TestModule(t *testing.T) {
+// build builds gobjdump itself with the given environment and returns the
+// path of the binary.
+func build(t testing.TB, env ...string) string {
 	wd, err := os.Getwd()
 	if err != nil {
 		panic(err)
 	}
 	rd := filepath.Dir(wd)
-	cmd := exec.Command("go", "build")
+	out := filepath.Join(t.TempDir(), "gobjdump")
+	cmd := exec.Command("go", "build", "-o", out)
 	cmd.Dir = rd
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	err = cmd.Run()
 	if err != nil {
 		panic(err)
 	}
+	return out
+}
 
-	f := Open(rd + "/gobjdump")
+func open(t *testing.T, path string) *ELF_Info {
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestModule(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
 	sb := strings.Builder{}
 	f.PrintModule(&sb)
-	t.Log("module layout:" + sb.String())
+	t.Log("layout " + f.ver.String() + ", module layout:" + sb.String())
+	if b := f.Summary(); b.Stripped || !strings.HasPrefix(b.GoVersion, "go") || b.Layout != f.ver.String() {
+		t.Errorf("summary %+v", b)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	for name, p := range map[string]func(io.Writer, string) error{
+		"pcsp": f.PrintPCSP,
+		"pcln": f.PrintPCLN,
+		"safe": f.PrintSafePoints,
+		"lp":   f.PrintLocalPointerMap,
+		"so":   f.PrintStackObjs,
+		"inl":  f.PrintInlTree,
+	} {
+		sb := strings.Builder{}
+		if err := p(&sb, "main.main"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		var fe *FuncNotFoundError
+		if err := p(&sb, "main.nosuchfunc"); !errors.As(err, &fe) {
+			t.Errorf("%s: expected function not found, got %v", name, err)
+		}
+		if !strings.HasPrefix(sb.String(), "main.main(") || !strings.Contains(sb.String(), "main.go") {
+			t.Errorf("%s: unexpected output:\n%s", name, sb.String())
+		}
+		if strings.Count(sb.String(), "\n") < 2 {
+			t.Errorf("%s: no data:\n%s", name, sb.String())
+		}
+	}
+	sb := strings.Builder{}
+	if err := f.PrintTypes(&sb); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(sb.String(), "*elf.ELF_Info") {
+		t.Errorf("type: *elf.ELF_Info not found")
+	}
+}
+
+// TestCrossArch decodes binaries of other pointer sizes and byte orders.
+func TestCrossArch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross compiling is slow")
+	}
+	for _, arch := range []string{"386", "arm", "mips", "ppc64", "s390x"} {
+		f := open(t, build(t, "GOARCH="+arch))
+		sb := strings.Builder{}
+		f.PrintPCSP(&sb, "main.main")
+		f.PrintStackObjs(&sb, "runtime.newproc")
+		f.PrintTypes(&sb)
+		f.PrintItabs(&sb, "io.Writer", "*os.File")
+		if a := f.Summary().Arch; a != arch {
+			t.Errorf("%s: arch %s", arch, a)
+		}
+		f.Close()
+		if !strings.Contains(sb.String(), "main.main(") || !strings.Contains(sb.String(), "gcbits:") ||
+			!strings.Contains(sb.String(), "*elf.ELF_Info") || !strings.Contains(sb.String(), " os.(*File).Write\n") {
+			t.Errorf("%s: unexpected output:\n%s", arch, sb.String()[:512])
+		}
+	}
+}
+
+func TestNotGo(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip(err)
+	}
+	if _, err := Open(sh); !errors.Is(err, ErrNotGoBinary) {
+		t.Errorf("expected not a Go binary, got %v", err)
+	}
+}
+
+func TestStripped(t *testing.T) {
+	f := open(t, build(t, "GOFLAGS=-ldflags=-s -w"))
+	defer f.Close()
+	if b := f.Summary(); !b.Stripped || b.Main != "github.com/voidpx/gobjdump" || b.Arch != runtime.GOARCH {
+		t.Errorf("summary %+v", b)
+	}
+	sb := strings.Builder{}
+	if err := f.PrintPCSP(&sb, "main.main"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.PrintTypes(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "main.go") || !strings.Contains(sb.String(), "*elf.ELF_Info") {
+		t.Errorf("unexpected output:\n%s", sb.String()[:512])
+	}
+}
+
+// TestReadAt checks that the binary reads the same mapped in memory, with
+// ReadAt and from OpenReader.
+func TestReadAt(t *testing.T) {
+	exe := build(t)
+	b, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out [3]string
+	for i := range out {
+		var f *ELF_Info
+		var err error
+		if i < 2 {
+			f, err = openFile(exe, i == 0)
+		} else {
+			f, err = OpenReader(bytes.NewReader(b), int64(len(b)))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mapped := f.obj.(*elfFile).m != nil; mapped != (i == 0) && runtime.GOOS == "linux" {
+			t.Errorf("%d: mapped %v", i, mapped)
+		}
+		sb := strings.Builder{}
+		f.PrintModule(&sb)
+		f.PrintBuildInfo(&sb)
+		f.PrintPCSP(&sb, "main.main")
+		f.PrintTypes(&sb)
+		f.PrintStackObjs(&sb, "runtime.newproc")
+		if err := f.Close(); err != nil {
+			t.Error(err)
+		}
+		out[i] = sb.String()
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i] != out[0] {
+			t.Errorf("mapped:\n%s\n%d:\n%s", out[0][:512], i, out[i][:512])
+		}
+	}
+	if _, err := OpenReader(bytes.NewReader(b[:4096]), 4096); err == nil {
+		t.Error("truncated binary opened")
+	}
+}
+
+// TestCrossOS decodes PE and Mach-O binaries, with and without symbols.
+func TestCrossOS(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross compiling is slow")
+	}
+	for _, env := range [][]string{
+		{"GOOS=windows", "GOARCH=amd64"},
+		{"GOOS=windows", "GOARCH=386", "GOFLAGS=-ldflags=-s -w"},
+		{"GOOS=darwin", "GOARCH=arm64"},
+		{"GOOS=darwin", "GOARCH=amd64", "GOFLAGS=-ldflags=-s -w"},
+	} {
+		f := open(t, build(t, env...))
+		sb := strings.Builder{}
+		f.PrintPCSP(&sb, "main.main")
+		f.PrintStackObjs(&sb, "runtime.newproc")
+		f.PrintTypes(&sb)
+		f.Close()
+		if !strings.Contains(sb.String(), "main.main(") || !strings.Contains(sb.String(), "gcbits:") ||
+			!strings.Contains(sb.String(), "*elf.ELF_Info") {
+			t.Errorf("%v: unexpected output:\n%s", env, sb.String()[:512])
+		}
+	}
+}
+
+// TestPCQuantum checks that the pc ranges of functions end where the next
+// function starts on architectures with fixed size instructions.
+func TestPCQuantum(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross compiling is slow")
+	}
+	for _, arch := range []string{"amd64", "arm64", "ppc64le", "riscv64", "loong64"} {
+		f := open(t, build(t, "GOARCH="+arch))
+		fn, err := f.lookupFunc("main.main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var next uint64
+		for i := 0; i < f.nfunc(); i++ {
+			if entry, _ := f.ftab(i); entry == fn.entry {
+				next, _ = f.ftab(i + 1)
+			}
+		}
+		pcsp := f.getpcvaluefunc(fn, func(f *_func) uint32 { return f.pcsp })
+		end := pcsp[len(pcsp)-1].pc_end
+		// functions are padded to the alignment of the architecture
+		if end > next || next-end >= 32 {
+			t.Errorf("%s: pcsp of main.main ends at %#x, next function at %#x", arch, end, next)
+		}
+		f.Close()
+	}
+}
+
+// callers returns the pcs of its callers, the runtime lists the calls
+// inlined at a pc as separate pcs.
+//
+//go:noinline
+func callers() []uintptr {
+	pcs := make([]uintptr, 8)
+	return pcs[:runtime.Callers(2, pcs)]
+}
+
+// inlinedCallers is inlined into its caller, the first pc returned is in the
+// inlined body.
+func inlinedCallers() []uintptr {
+	return callers()
+}
+
+// TestPC compares the frames at a pc of the test binary with those of the
+// runtime.
+func TestPC(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	pcs := inlinedCallers()
+	pc := pcs[0]
+	var want []string
+	frames := runtime.CallersFrames(pcs)
+	for {
+		fr, more := frames.Next()
+		want = append(want, fmt.Sprintf("%s %s:%d", fr.Function, fr.File, fr.Line))
+		if !more || fr.Function == "github.com/voidpx/gobjdump/elf.TestPC" {
+			break
+		}
+	}
+	f := open(t, exe)
+	defer f.Close()
+	if err := f.loadpcln(); err != nil {
+		t.Fatal(err)
+	}
+	fn := f.findFuncPC(uint64(pc - 1))
+	if fn == nil {
+		t.Fatalf("no function at %#x", pc-1)
+	}
+	var got []string
+	for _, fr := range f.frames(fn, uint64(pc-1)) {
+		got = append(got, fmt.Sprintf("%s %s:%d", fr.Func, fr.File, fr.Line))
+	}
+	if len(got) < 2 || strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("frames at %#x:\n%s\nwant:\n%s", pc-1, strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	var pe *PCNotFoundError
+	if err := f.PrintPC(io.Discard, 1); !errors.As(err, &pe) {
+		t.Errorf("expected pc not found, got %v", err)
+	}
+}
+
+func TestTypeDescs(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	sb := strings.Builder{}
+	if err := f.PrintTypeDescs(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "elem: elf.ELF_Info (") {
+		t.Fatalf("type: *elf.ELF_Info not expanded")
+	}
+	var info *typeDesc
+	for _, p := range f.types {
+		if f.typeName(p) == "*elf.ELF_Info" {
+			info = f.decodeType(f.decodeType(p).elem)
+		}
+	}
+	if info.kind != kindStruct || f.pkgPath(info) != "github.com/voidpx/gobjdump/elf" {
+		t.Fatalf("elf.ELF_Info: kind %s, pkgpath %s", info.kind, f.pkgPath(info))
+	}
+	var obj *typeDesc
+	var fields []string
+	for _, fd := range info.struct_.fields {
+		fields = append(fields, f.name(fd.name))
+		if f.name(fd.name) == "obj" {
+			obj = f.decodeType(fd.typ)
+		}
+	}
+	if obj == nil || obj.kind != kindInterface {
+		t.Fatalf("elf.ELF_Info: no interface field obj in %v", fields)
+	}
+	var methods []string
+	for _, m := range obj.iface.methods {
+		methods = append(methods, f.name(f.module.types+uint64(m.name)))
+	}
+	if strings.Join(methods, " ") != "Close arch debugSection decoder dwarf pie sections symbol" {
+		t.Errorf("elf.objFile: methods %v", methods)
+	}
+}
+
+type testTagged struct {
+	Name  string `json:"name" yaml:"name"`
+	Port  int    `json:"port,omitempty"`
+	plain bool
+}
+
+// the map type lists testTagged among the types of the test binary
+var testTaggedMap = map[string]testTagged{"a": {Name: "a"}}
+
+func TestStructTags(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	f := open(t, exe)
+	defer f.Close()
+	sb := strings.Builder{}
+	if err := f.PrintStructTags(&sb, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "elf.testTagged\n" +
+		"    Name string `json:\"name\" yaml:\"name\"`\n" +
+		"    Port int `json:\"port,omitempty\"`\n" +
+		"    plain bool\n"
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("%s not found in:\n%s", want, sb.String())
+	}
+}
+
+func TestMethods(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	sb := strings.Builder{}
+	if err := f.PrintMethods(&sb, "*elf.ELF_Info"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"pkgpath: github.com/voidpx/gobjdump/elf\n",
+		"    Close: func() error\n        ifn: ",
+		" github.com/voidpx/gobjdump/elf.(*ELF_Info).Close\n",
+		"ifn: -1 (eliminated)",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("%q not found in:\n%s", want, sb.String())
+		}
+	}
+	var te *TypeNotFoundError
+	if err := f.PrintMethods(&sb, "nosuchtype"); !errors.As(err, &te) {
+		t.Errorf("expected type not found, got %v", err)
+	}
+}
+
+func TestItabs(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	sb := strings.Builder{}
+	if err := f.PrintItabs(&sb, "elf.objFile", "*elf.peFile"); err != nil {
+		t.Fatal(err)
+	}
+	want := ": *elf.peFile -> elf.objFile\n" +
+		"    Close: 0x"
+	if !strings.Contains(sb.String(), want) || strings.Count(sb.String(), "github.com/voidpx/gobjdump/elf.(*peFile).") != 8 {
+		t.Errorf("unexpected output:\n%s", sb.String())
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	f := open(t, build(t, "GOFLAGS=-trimpath"))
+	defer f.Close()
+	sb := strings.Builder{}
+	if err := f.PrintBuildInfo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"go\t" + runtime.Version() + "\n",
+		"path\tgithub.com/voidpx/gobjdump\n",
+		"dep\tgithub.com/spf13/cobra\t",
+		"build\t-trimpath=true\n",
+		"build\tGOARCH=" + runtime.GOARCH + "\n",
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("%q not found in:\n%s", want, sb.String())
+		}
+	}
+	if f.release != parseRelease(runtime.Version()) {
+		t.Errorf("release %d, built with %s", f.release, runtime.Version())
+	}
+	for v, r := range map[string]int{"go1.16": 16, "go1.21.3": 21, "go1.22rc1": 22, "devel go1.27-abcdef": 27, "gccgo": 0} {
+		if parseRelease(v) != r {
+			t.Errorf("parseRelease(%q) = %d, want %d", v, parseRelease(v), r)
+		}
+	}
+}
+
+// TestJSON checks the data model carries everything the text shows: it
+// renders the same once through JSON.
+func TestJSON(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	roundTrip := func(name string, v textWriter, err error, decoded textWriter) {
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := json.Unmarshal(b, decoded); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want, got := strings.Builder{}, strings.Builder{}
+		v.WriteText(&want)
+		decoded.WriteText(&got)
+		if got.String() != want.String() {
+			t.Errorf("%s: got\n%s\nwant:\n%s", name, got.String(), want.String())
+		}
+		if want.Len() == 0 {
+			t.Errorf("%s: no data", name)
+		}
+	}
+	mod, err := f.ModuleData()
+	roundTrip("mod", mod, err, &ModuleData{})
+	bi, err := f.BuildInfo()
+	roundTrip("buildinfo", bi, err, &BuildInfo{})
+	files, err := f.FuncsByFile()
+	roundTrip("func", files, err, &Files{})
+	types, err := f.Types(true)
+	roundTrip("type", types, err, &Types{})
+	structs, err := f.StructTags(false)
+	roundTrip("tags", structs, err, &Structs{})
+	methods, err := f.Methods("*elf.ELF_Info")
+	roundTrip("methods", methods, err, &MethodSets{})
+	itabs, err := f.Itabs("", "")
+	roundTrip("itab", itabs, err, &Itabs{})
+	for name, get := range map[string]func(string) (*PCValues, error){"pcsp": f.PCSP, "pcln": f.PCLN, "safe": f.SafePoints} {
+		tab, err := get("main.main")
+		roundTrip(name, tab, err, &PCValues{})
+	}
+	lp, err := f.LocalPointerMap("main.main")
+	roundTrip("lp", lp, err, &PointerMap{})
+	so, err := f.StackObjects("main.main")
+	roundTrip("so", so, err, &StackObjects{})
+	inl, err := f.InlTree("main.main")
+	roundTrip("inl", inl, err, &InlTree{})
+	pcsp, err := f.PCSP("main.main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcs, err := f.PCInfos(pcsp.Values[0].Start, pcsp.Values[len(pcsp.Values)-1].Start)
+	roundTrip("pc", pcs, err, &PCInfos{})
+}
+
+func TestFuncAPI(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	fn, err := f.Func("main.main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.End <= fn.Entry || !strings.HasSuffix(fn.File, "main.go") || fn.FrameSize == 0 {
+		t.Errorf("unexpected main.main: %+v", fn)
+	}
+	if at, err := f.FuncForPC(fn.End - 1); err != nil || at.Name != fn.Name {
+		t.Errorf("function at %#x: %v %v", fn.End-1, at, err)
+	}
+	pcsp, err := f.PCSP("main.main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []PCValue
+	for it := fn.PCSP(); it.Next(); {
+		got = append(got, PCValue{Start: it.Start(), End: it.End(), Value: it.Value()})
+	}
+	if fmt.Sprint(got) != fmt.Sprint(pcsp.Values) {
+		t.Errorf("pcsp iterator: %v\nwant: %v", got, pcsp.Values)
+	}
+	files := 0
+	for it := fn.PCFile(); it.Next(); {
+		if fn.FileName(it.Value()) == fn.File {
+			files++
+		}
+	}
+	if files == 0 {
+		t.Errorf("pcfile: %s not found", fn.File)
+	}
+	if it := fn.PCData(1 << 20); it.Next() || it.Err() != nil {
+		t.Errorf("missing pcdata table: %v", it.Err())
+	}
+
+	m := f.Module()
+	if m.Layout() != f.ver.String() || m.PtrSize() != 8 && m.PtrSize() != 4 || m.Quantum() == 0 {
+		t.Errorf("module: layout %s, ptrsize %d, quantum %d", m.Layout(), m.PtrSize(), m.Quantum())
+	}
+	if start, end := m.Text(); fn.Entry < start || fn.End > end {
+		t.Errorf("main.main not in text [%#x, %#x)", start, end)
+	}
+	if v, ok := m.Field("minpc"); !ok || v != f.module.minpc {
+		t.Errorf("minpc field: %#x", v)
+	}
+	prev := uint64(0)
+	for i := 0; i < m.NumFunc(); i += m.NumFunc() / 100 {
+		fi, err := m.FuncAt(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Entry < prev {
+			t.Errorf("function %d %s at %#x before the previous one", i, fi.Name, fi.Entry)
+		}
+		prev = fi.Entry
+	}
+	if _, err := m.FuncAt(m.NumFunc()); err == nil {
+		t.Errorf("expected index out of range")
+	}
+	names, err := m.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, n := range names {
+		found = found || n == fn.File
+	}
+	if !found {
+		t.Errorf("%s not in the file table of %d files", fn.File, len(names))
+	}
+}
+
+func TestSelectFuncs(t *testing.T) {
+	exe := build(t)
+	f := open(t, exe)
+	defer f.Close()
+	for _, c := range []struct {
+		s    FuncSelector
+		want string
+	}{
+		{FuncSelector{Patterns: []string{"main.main"}}, "main.main"},
+		{FuncSelector{Patterns: []string{"main.main", "main.exitCode"}}, "main.exitCode main.main"},
+		{FuncSelector{Patterns: []string{"main.ma?n", "main.main"}}, "main.main"},
+		{FuncSelector{Patterns: []string{`/^main\.main\.func1$/`}}, "main.main.func1"},
+		{FuncSelector{Patterns: []string{"*.(*ELF_Info).Close"}, Packages: []string{"github.com/voidpx/gobjdump/elf"}},
+			"github.com/voidpx/gobjdump/elf.(*ELF_Info).Close"},
+	} {
+		names, err := f.SelectFuncs(c.s)
+		if err != nil {
+			t.Errorf("%+v: %v", c.s, err)
+		} else if got := strings.Join(names, " "); got != c.want {
+			t.Errorf("%+v: got %s, want %s", c.s, got, c.want)
+		}
+	}
+	names, err := f.SelectFuncs(FuncSelector{Packages: []string{"main"}})
+	if err != nil || len(names) < 2 {
+		t.Fatalf("package main: %v %v", names, err)
+	}
+	for _, n := range names {
+		if !strings.HasPrefix(n, "main.") {
+			t.Errorf("package main: %s selected", n)
+		}
+	}
+	all, err := f.SelectFuncs(FuncSelector{All: true})
+	if err != nil || len(all) < f.nfunc()*9/10 {
+		t.Fatalf("all: %d of %d functions, %v", len(all), f.nfunc(), err)
+	}
+	var fe *FuncNotFoundError
+	for _, s := range []FuncSelector{{Patterns: []string{"main.nosuchfunc*"}}, {Packages: []string{"nosuchpkg"}}} {
+		if _, err := f.SelectFuncs(s); !errors.As(err, &fe) {
+			t.Errorf("%+v: expected function not found, got %v", s, err)
+		}
+	}
+	for name, want := range map[string]string{
+		"net/http.(*Server).Serve":                "net/http",
+		"main.main.func1":                         "main",
+		"main.F[go.shape.string,net/http.Header]": "main",
+		"runtime.morestack":                       "runtime",
+	} {
+		if got := funcPackage(name); got != want {
+			t.Errorf("package of %s: %s, want %s", name, got, want)
+		}
+	}
+
+	// the same dumps from several goroutines on a fresh file
+	g := open(t, exe)
+	defer g.Close()
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for w := range errs {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(names); i += len(errs) {
+				got, err := g.LocalPointerMap(names[i])
+				if err != nil {
+					errs[w] = err
+					return
+				}
+				want, _ := f.LocalPointerMap(names[i])
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					errs[w] = fmt.Errorf("%s: got %v, want %v", names[i], got, want)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFuncIndex(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	if err := f.loadpcln(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < f.nfunc(); i++ {
+		entry, off := f.ftab(i)
+		name := f.getFuncName(f.funcAt(off))
+		if fn := f.findFunc(name); fn == nil || f.getFuncName(fn) != name {
+			t.Fatalf("%s not found by name", name)
+		}
+		if fn := f.findFuncPC(entry); fn == nil || fn.entry != entry {
+			t.Fatalf("%s not found at its entry %#x", name, entry)
+		}
+	}
+	fns, err := f.FuncsWithPrefix("main.main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fns) < 2 || fns[0].Name != "main.main" {
+		t.Fatalf("functions with prefix main.main: %v", fns)
+	}
+	for i, fn := range fns {
+		if !strings.HasPrefix(fn.Name, "main.main") {
+			t.Errorf("function %d with prefix main.main: %s", i, fn.Name)
+		}
+		if i > 0 && fns[i-1].Name > fn.Name {
+			t.Errorf("function %d with prefix main.main: %s after %s", i, fn.Name, fns[i-1].Name)
+		}
+	}
+	if fns, err := f.FuncsWithPrefix("main.nosuchfunc"); err != nil || len(fns) != 0 {
+		t.Errorf("functions with prefix main.nosuchfunc: %v %v", fns, err)
+	}
+}
+
+// testProg crashes, dereferencing nil with an argument, or sleeps with
+// sleep, blocking 3 goroutines in main.block.
+const testProg = `package main
+
+import (
+	"os"
+	"time"
+)
+
+func block(c chan int) { <-c }
+
+func main() {
+	c := make(chan int)
+	for i := 0; i < 3; i++ {
+		go block(c)
+	}
+	time.Sleep(100 * time.Millisecond)
+	switch {
+	case len(os.Args) > 1 && os.Args[1] == "sleep":
+		os.Stdout.WriteString("ready\n")
+		time.Sleep(time.Hour)
+	case len(os.Args) > 1:
+		var p *int
+		*p = 1
+	}
+	panic("boom")
+}
+`
+
+// buildTestProg builds testProg in dir with the given build mode.
+func buildTestProg(t *testing.T, dir, buildmode string) string {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "go.mod"), []byte("module crash\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "main.go"), []byte(testProg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prog := filepath.Join(dir, "crash")
+	cmd := exec.Command("go", "build", "-buildmode="+buildmode, "-o", prog)
+	cmd.Dir = src
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %v\n%s", buildmode, err, out)
+	}
+	return prog
+}
+
+// checkBlocked checks that gs has the 3 goroutines of testProg blocked in
+// main.block, inlined in its closure.
+func checkBlocked(t *testing.T, mode string, gs Goroutines) {
+	var blocked int
+	for _, g := range gs {
+		if g.WaitReason != "chan receive" {
+			continue
+		}
+		blocked++
+		if g.Error != "" {
+			t.Errorf("%s: goroutine %d: %s", mode, g.ID, g.Error)
+		}
+		var inlined bool
+		for _, sf := range g.Stack {
+			for _, fr := range sf.Frames {
+				inlined = inlined || fr.Func == "main.block" && fr.Inlined && fr.Line == 8
+			}
+		}
+		if !inlined {
+			t.Errorf("%s: goroutine %d: no inlined main.block frame in %+v", mode, g.ID, g.Stack)
+		}
+		if g.CreatedBy == nil || g.CreatedBy.Func != "main.main" || g.Parent != 1 {
+			t.Errorf("%s: goroutine %d created by %+v in %d", mode, g.ID, g.CreatedBy, g.Parent)
+		}
+	}
+	if blocked != 3 {
+		t.Errorf("%s: %d goroutines blocked on chan receive, want 3", mode, blocked)
+	}
+}
+
+// crashTestProg builds testProg with the given build mode and runs it with
+// args to dump a core file, skipping the test if there is none.
+func crashTestProg(t *testing.T, buildmode, args string) (*ELF_Info, *Core) {
+	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64") {
+		t.Skip("core files are read on linux/amd64 and linux/arm64")
+	}
+	dir := t.TempDir()
+	prog := buildTestProg(t, dir, buildmode)
+	cmd := exec.Command("sh", "-c", "ulimit -c unlimited; exec ./crash "+args)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOTRACEBACK=crash")
+	cmd.Run()
+	cores, _ := filepath.Glob(filepath.Join(dir, "core*"))
+	if len(cores) == 0 {
+		t.Skip("no core file dumped, see /proc/sys/kernel/core_pattern")
+	}
+	core, err := OpenCore(cores[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { core.Close() })
+	f := open(t, prog)
+	t.Cleanup(func() { f.Close() })
+	return f, core
+}
+
+// TestCore crashes a program, plain and PIE, and reads its goroutines from
+// the core file it dumps.
+func TestCore(t *testing.T) {
+	for _, c := range []struct {
+		buildmode string
+		args      string
+		top       string // the frame below the runtime in goroutine 1
+	}{
+		{"exe", "", "main.main"},
+		{"pie", "nil", "runtime.sigpanic"},
+	} {
+		f, core := crashTestProg(t, c.buildmode, c.args)
+		gs, err := f.Goroutines(core.Process(f))
+		if err != nil {
+			t.Fatalf("%s: %v", c.buildmode, err)
+		}
+		checkBlocked(t, c.buildmode, gs)
+		// the threads end up in the runtime, at the top of their stack
+		for _, th := range core.Threads() {
+			frames, err := f.Unwind(core.Process(f), th.PC, th.SP, th.LR)
+			if err != nil || len(frames) < 2 || !strings.HasPrefix(frames[len(frames)-1].Func, "runtime.") {
+				t.Errorf("%s: thread %d: %v %+v", c.buildmode, th.ID, err, frames)
+			}
+		}
+		var main *Goroutine
+		for i := range gs {
+			if gs[i].ID == 1 {
+				main = &gs[i]
+			}
+		}
+		if main == nil || main.Status != "running" || main.Thread == 0 || main.Error != "" {
+			t.Fatalf("%s: goroutine 1: %+v", c.buildmode, main)
+		}
+		var names []string
+		for _, sf := range main.Stack {
+			names = append(names, sf.Func)
+		}
+		want := []string{c.top, "main.main", "runtime.main", "runtime.goexit"}
+		if c.top == "main.main" {
+			want = want[1:]
+		}
+		if len(names) < len(want) || strings.Join(names[len(names)-len(want):], " ") != strings.Join(want, " ") {
+			t.Errorf("%s: goroutine 1 stack %s, want it to end with %s", c.buildmode, names, want)
+		}
+	}
+}
+
+// TestProc reads the goroutines of testProg while it sleeps.
+func TestProc(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("processes are read through /proc on linux")
+	}
+	for _, buildmode := range []string{"exe", "pie"} {
+		cmd := exec.Command(buildTestProg(t, t.TempDir(), buildmode), "sleep")
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer cmd.Wait()
+		defer cmd.Process.Kill()
+		if _, err := bufio.NewReader(out).ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		p, err := OpenProc(cmd.Process.Pid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		f := open(t, p.Exe())
+		defer f.Close()
+		pr, err := p.Process(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gs, err := f.Goroutines(pr)
+		if err != nil {
+			t.Fatalf("%s: %v", buildmode, err)
+		}
+		checkBlocked(t, buildmode, gs)
+		if len(gs) == 0 {
+			t.Fatalf("%s: no goroutines", buildmode)
+		}
+		if gs[0].ID != 1 || gs[0].WaitReason != "sleep" || len(gs[0].Stack) < 3 || gs[0].Stack[len(gs[0].Stack)-3].Func != "main.main" {
+			t.Errorf("%s: goroutine 1 not sleeping in main.main: %+v", buildmode, gs[0])
+		}
+	}
+}
+
+// TestUnwind walks the stack of the main goroutine of testProg while it
+// sleeps, from the registers it saved, plain and PIE.
+func TestUnwind(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("processes are read through /proc on linux")
+	}
+	for _, buildmode := range []string{"exe", "pie"} {
+		cmd := exec.Command(buildTestProg(t, t.TempDir(), buildmode), "sleep")
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		defer cmd.Wait()
+		defer cmd.Process.Kill()
+		if _, err := bufio.NewReader(out).ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		p, err := OpenProc(cmd.Process.Pid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		f := open(t, p.Exe())
+		defer f.Close()
+		pr, err := p.Process(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (pr.Bias != 0) != (buildmode == "pie") {
+			t.Errorf("%s: bias %#x", buildmode, pr.Bias)
+		}
+		// the registers saved in g.sched of goroutine 1
+		l := f.runtimeLayout()
+		m := &memory{f, pr.Mem, pr.Bias}
+		g := m.uintptr(m.uintptr(l.allgs + pr.Bias))
+		sched := g + l.fields["g.sched"].off
+		pc, sp, lr := l.field(m, sched, "gobuf.pc"), l.field(m, sched, "gobuf.sp"), l.field(m, sched, "gobuf.lr")
+		if id := l.field(m, g, "g.goid"); id != 1 {
+			t.Fatalf("%s: allgs[0] is goroutine %d", buildmode, id)
+		}
+		frames, err := f.Unwind(pr, pc, sp, lr)
+		if err != nil {
+			t.Fatalf("%s: %v", buildmode, err)
+		}
+		if len(frames) == 0 || frames[0].PC != pc || frames[0].SP != sp {
+			t.Fatalf("%s: innermost frame %+v, pc %#x sp %#x", buildmode, frames, pc, sp)
+		}
+		var fns []string
+		for _, sf := range frames {
+			fns = append(fns, sf.Func)
+			if sf.SP < sp || sf.FP <= sf.SP {
+				t.Errorf("%s: frame %+v", buildmode, sf)
+			}
+		}
+		if n := len(fns); n < 3 || strings.Join(fns[n-3:], " ") != "main.main runtime.main runtime.goexit" {
+			t.Errorf("%s: stack %v", buildmode, fns)
+		}
+	}
+}
+
+// TestMemory checks that what a process lacks is read from the binary only
+// if it is read-only there.
+func TestMemory(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	if err := f.loadpcln(); err != nil {
+		t.Fatal(err)
+	}
+	m := &memory{f, bytes.NewReader(nil), 0}
+	read := func(addr uint64) (err error) {
+		defer catch(&err)
+		m.uintptr(addr)
+		return nil
+	}
+	if err := read(f.module.text); err != nil {
+		t.Errorf("text: %v", err)
+	}
+	// in .noptrdata, initialized in the file
+	md, ok := f.obj.symbol(FIRST_MOD_SYM)
+	if !ok {
+		t.Fatal(FIRST_MOD_SYM + " not found")
+	}
+	if err := read(md); err == nil {
+		t.Error(FIRST_MOD_SYM + " read from the binary")
+	}
+}
+
+func TestFrameGeometry(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	if f.obj.arch() != "amd64" {
+		t.Skip("frames laid out for amd64")
+	}
+	for _, c := range []struct {
+		size int
+		want frameGeometry
+	}{
+		// only the return address, no frame pointer saved
+		{0, frameGeometry{fp: 8, varp: 0, argp: 8}},
+		{0x18, frameGeometry{fp: 0x20, varp: 0x10, argp: 0x20, savedFP: true}},
+	} {
+		if got := f.frameGeometry(c.size); got != c.want {
+			t.Errorf("frame of size %#x: got %+v, want %+v", c.size, got, c.want)
+		}
+	}
+}
+
+func TestGoroutinesNoDWARF(t *testing.T) {
+	f := open(t, build(t, "GOFLAGS=-ldflags=-w"))
+	defer f.Close()
+	if _, err := f.Goroutines(&Process{Mem: bytes.NewReader(nil)}); !errors.Is(err, ErrNoDWARF) {
+		t.Errorf("got %v, want %v", err, ErrNoDWARF)
+	}
+}
+
+// TestFrameLayout checks the frames of the goroutines blocked in a core
+// file against their stacks: the return address of each is the pc of its
+// caller and the saved frame pointer points into the frame of the caller.
+func TestFrameLayout(t *testing.T) {
+	f, core := crashTestProg(t, "exe", "")
+	gs, err := f.Goroutines(core.Process(f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := int64(f.Module().PtrSize())
+	var n int
+	for _, g := range gs {
+		if g.WaitReason != "chan receive" {
+			continue
+		}
+		for i, sf := range g.Stack[:len(g.Stack)-1] {
+			l, err := f.FrameLayout(sf.Func, sf.PC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := int64(sf.FP - sf.SP); got != l.Slots[0].SP-l.Slots[0].FP {
+				t.Errorf("%s: fp-sp %#x, frame size %#x", sf.Func, got, l.Slots[0].SP-l.Slots[0].FP)
+			}
+			for _, s := range l.Slots {
+				b := make([]byte, w)
+				if _, err := core.ReadAt(b, int64(sf.SP)+s.SP); err != nil {
+					t.Fatal(err)
+				}
+				v := f.dec.uintptr(b)
+				switch s.Kind {
+				case "return address":
+					n++
+					if v != g.Stack[i+1].PC {
+						t.Errorf("%s: return address %#x, caller pc %#x", sf.Func, v, g.Stack[i+1].PC)
+					}
+				case "frame pointer":
+					// but that of the first frame, 0
+					if up := g.Stack[i+1]; i+2 < len(g.Stack) && (v < up.SP || v >= up.FP) {
+						t.Errorf("%s: saved frame pointer %#x not in %s frame [%#x, %#x)", sf.Func, v, up.Func, up.SP, up.FP)
+					}
+				}
+			}
+		}
+	}
+	if n == 0 {
+		t.Error("no return address found")
+	}
+	if _, err := f.FrameLayout("main.main", 0); !errors.As(err, new(*PCNotFoundError)) {
+		t.Errorf("pc 0: %v", err)
+	}
+}
+
+func TestPointerMapVars(t *testing.T) {
+	f := open(t, build(t))
+	defer f.Close()
+	// has reports whether bit of m, any if it is -1, is named name of type typ
+	has := func(m *PointerMap, err error, bit int, name, typ string) bool {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range m.Ranges {
+			for _, v := range r.Vars {
+				if (bit < 0 || v.Bit == bit) && v.Name == name && v.Type == typ && v.Off == 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	// func chanrecv(c *hchan, ep unsafe.Pointer, block bool) (selected, received bool)
+	if m, err := f.ArgPointerMap("runtime.chanrecv"); !has(m, err, 0, "c", "*runtime.hchan") || !has(m, err, 1, "ep", "unsafe.Pointer") {
+		t.Errorf("c and ep not found in the argument pointer map %+v", m)
+	}
+	// (*maybeTraceablePtr).set, inlined, takes c and ep as v: the words are theirs alone
+	m, err := f.ArgPointerMap("runtime.chanrecv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range m.Ranges {
+		bits := map[int]int{}
+		for _, v := range r.Vars {
+			if bits[v.Bit]++; v.Name == "v" || bits[v.Bit] > 1 {
+				t.Errorf("inlined parameter alias reported in %+v", r)
+			}
+		}
+	}
+	if m, err := f.LocalPointerMap("runtime.chanrecv"); !has(m, err, -1, "gp", "*runtime.g") {
+		t.Errorf("gp not found in the local pointer map %+v", m)
+	}
+
+	g := open(t, build(t, "GOFLAGS=-ldflags=-w"))
+	defer g.Close()
+	m, err = g.ArgPointerMap("runtime.chanrecv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range m.Ranges {
+		if r.Vars != nil {
+			t.Errorf("variables without DWARF: %+v", r)
+		}
+	}
+}
+
+// TestLayouts decodes binaries built by testdata/gen.sh with the oldest
+// toolchain at hand for each layout the current one does not produce.
+func TestLayouts(t *testing.T) {
+	for _, c := range []struct{ release, layout string }{
+		{"go1.20.14", "go1.20"},
+		{"go1.21.13", "go1.21"},
+	} {
+		t.Run(c.release, func(t *testing.T) {
+			z, err := os.Open(filepath.Join("testdata", "layout-"+c.release+".gz"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer z.Close()
+			zr, err := gzip.NewReader(z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := OpenReader(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if s := f.Summary(); s.GoVersion != c.release || s.Layout != c.layout || s.Stripped {
+				t.Fatalf("summary %+v", s)
+			}
+			for _, p := range []struct {
+				print func(io.Writer) error
+				want  string
+			}{
+				{f.PrintModule, "ftab"},
+				{f.PrintFuncs, "main.describe"},
+				{func(w io.Writer) error { return f.PrintPCLN(w, "main.main") }, ": 32\n"},
+				{func(w io.Writer) error { return f.PrintPCSP(w, "main.describe") }, ": 0x68\n"},
+				{func(w io.Writer) error { return f.PrintInlTree(w, "main.main") }, "fmt.Println, parent: -1, call: ./main.go:32"},
+				{f.PrintTypeDescs, "main.Config"},
+				{func(w io.Writer) error { return f.PrintStructTags(w, false) }, "    Port int `json:\"port,omitempty\"`\n"},
+				{func(w io.Writer) error { return f.PrintMethods(w, "*main.Square") }, " main.(*Square).Area\n"},
+				{func(w io.Writer) error { return f.PrintItabs(w, "main.Shape", "*main.Square") }, " main.(*Square).Name\n"},
+				{f.PrintBuildInfo, "go\t" + c.release + "\n"},
+			} {
+				sb := strings.Builder{}
+				if err := p.print(&sb); err != nil {
+					t.Error(err)
+				} else if !strings.Contains(sb.String(), p.want) {
+					t.Errorf("%q not found in:\n%s", p.want, sb.String())
+				}
+			}
+		})
+	}
 }
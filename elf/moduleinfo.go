@@ -0,0 +1,147 @@
+package elf
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Module gives access to the moduledata of the binary and the function
+// table it refers to.
+type Module struct {
+	e *ELF_Info
+}
+
+// Module returns the moduledata of the binary.
+func (e *ELF_Info) Module() *Module {
+	return &Module{e}
+}
+
+// GoVersion returns the Go version the binary was built with, from its build
+// info, "" if it has none.
+func (m *Module) GoVersion() string {
+	return m.e.buildVersion
+}
+
+// Summary returns the Go version, main module, architecture and layout of
+// the binary, and whether it is stripped or position independent.
+func (e *ELF_Info) Summary() *Binary {
+	b := &Binary{
+		GoVersion: e.buildVersion,
+		Arch:      e.obj.arch(),
+		Stripped:  e.stripped,
+		PIE:       e.obj.pie(),
+		Layout:    e.ver.String(),
+	}
+	if bi, err := e.BuildInfo(); err == nil {
+		b.Main = bi.Main.Path
+		if b.Main == "" {
+			// commands of the standard distribution have no main module
+			b.Main = bi.Path
+		}
+	}
+	return b
+}
+
+// Layout returns the oldest Go release with the layout of the runtime data
+// of the binary, e.g. go1.20.
+func (m *Module) Layout() string {
+	return m.e.ver.String()
+}
+
+// PtrSize returns the pointer size of the target.
+func (m *Module) PtrSize() int {
+	return m.e.dec.ptrSize
+}
+
+// ByteOrder returns the byte order of the target.
+func (m *Module) ByteOrder() binary.ByteOrder {
+	return m.e.dec.order
+}
+
+// Quantum returns the instruction size quantum pcs are encoded in, e.g. 4 on
+// arm64.
+func (m *Module) Quantum() int {
+	return int(m.e.header.minLC)
+}
+
+// Text returns the range of the text section.
+func (m *Module) Text() (start, end uint64) {
+	return m.e.module.text, m.e.module.etext
+}
+
+// PCRange returns the range of pcs covered by the function table.
+func (m *Module) PCRange() (min, max uint64) {
+	return m.e.module.minpc, m.e.module.maxpc
+}
+
+// Data returns the range of the data with pointers.
+func (m *Module) Data() (start, end uint64) {
+	return m.e.module.data, m.e.module.edata
+}
+
+// NoPtrData returns the range of the data without pointers.
+func (m *Module) NoPtrData() (start, end uint64) {
+	return m.e.module.noptrdata, m.e.module.enoptrdata
+}
+
+// BSS returns the range of the zeroed data with pointers.
+func (m *Module) BSS() (start, end uint64) {
+	return m.e.module.bss, m.e.module.ebss
+}
+
+// NoPtrBSS returns the range of the zeroed data without pointers.
+func (m *Module) NoPtrBSS() (start, end uint64) {
+	return m.e.module.noptrbss, m.e.module.enoptrbss
+}
+
+// Types returns the range of the type descriptors and, as of go1.27, itabs.
+func (m *Module) Types() (start, end uint64) {
+	return m.e.module.types, m.e.module.etypes
+}
+
+// Field returns the value of the moduledata field named name, see
+// ModuleData for the names.
+func (m *Module) Field(name string) (uint64, bool) {
+	for _, f := range m.e.module.fields {
+		if f.name == name {
+			return f.value, true
+		}
+	}
+	return 0, false
+}
+
+// NumFunc returns the number of functions in the function table.
+func (m *Module) NumFunc() int {
+	return m.e.nfunc()
+}
+
+// FuncAt returns the i-th function of the function table, they are sorted
+// by entry.
+func (m *Module) FuncAt(i int) (fn *Func, err error) {
+	defer catch(&err)
+	if err := m.e.loadpcln(); err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= m.e.nfunc() {
+		return nil, fmt.Errorf("function index %d out of range [0, %d)", i, m.e.nfunc())
+	}
+	_, off := m.e.ftab(i)
+	return m.e.newFunc(m.e.funcAt(off)), nil
+}
+
+// Files returns the names of the source files in the file table.
+func (m *Module) Files() (files []string, err error) {
+	defer catch(&err)
+	if err := m.e.loadpcln(); err != nil {
+		return nil, err
+	}
+	for b := m.e.tab.filetab; len(b) > 0; {
+		name := toString(b)
+		if indexByte(b, 0) < 0 {
+			break
+		}
+		files = append(files, name)
+		b = b[len(name)+1:]
+	}
+	return files, nil
+}
@@ -0,0 +1,516 @@
+package elf
+
+import (
+	"bytes"
+	"debug/dwarf"
+	felf "debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// objFile is the object file holding the Go binary, one of ELF, PE or
+// Mach-O.
+type objFile interface {
+	io.Closer
+	// sections returns the sections loaded in memory when the binary runs.
+	sections() []*section
+	// symbol returns the address of the named symbol, if the file has a
+	// symbol table.
+	symbol(name string) (uint64, bool)
+	// decoder returns the decoder for the pointer size and byte order of the
+	// target.
+	decoder() decoder
+	// arch returns the GOARCH of the target, "" if it is not a Go one.
+	arch() string
+	// pie reports whether the binary is position independent.
+	pie() bool
+	// dwarf returns the debugging information of the binary.
+	dwarf() (*dwarf.Data, error)
+	// debugSection returns the contents of the DWARF section name, e.g.
+	// loclists for .debug_loclists, nil if there is none.
+	debugSection(name string) []byte
+}
+
+// section is a section of an objFile mapped at addr. The first filesz bytes
+// of it come from the file, the rest is zeroed at run time. Its data points
+// into the file if it is mapped in memory.
+type section struct {
+	mu       sync.Mutex // guards data
+	name     string
+	addr     uint64
+	size     uint64
+	filesz   uint64
+	writable bool
+	r        io.ReaderAt
+	data     []byte
+}
+
+// contents returns the part of s backed by the file, read on first use.
+func (s *section) contents() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil && s.filesz > 0 {
+		b := make([]byte, s.filesz)
+		if _, err := s.r.ReadAt(b, 0); err != nil && err != io.EOF {
+			fail(fmt.Errorf("section %s: %w", s.name, err))
+		}
+		s.data = b
+	}
+	return s.data
+}
+
+// openObjFile opens the named file with the backend matching its format. It
+// is mapped in memory if mmap is set and the system allows it, and read with
+// ReadAt otherwise.
+func openObjFile(name string, mmap bool) (objFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	src := &source{c: f}
+	var r io.ReaderAt = f
+	if mmap {
+		if m, err := mmapFile(f); err == nil {
+			src.m = m
+			r = bytes.NewReader(m)
+		}
+	}
+	o, err := newObjFile(r, src)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+func newObjFile(r io.ReaderAt, src *source) (objFile, error) {
+	var magic [4]byte
+	if _, err := r.ReadAt(magic[:], 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotGoBinary, err)
+	}
+	switch objFormat(magic[:]) {
+	case "elf":
+		ef, err := felf.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotGoBinary, err)
+		}
+		return &elfFile{ef, src}, nil
+	case "pe":
+		pf, err := pe.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotGoBinary, err)
+		}
+		return &peFile{pf, src}, nil
+	case "macho":
+		mf, err := macho.NewFile(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotGoBinary, err)
+		}
+		return &machoFile{mf, src}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown object file format", ErrNotGoBinary)
+}
+
+// objFormat returns the format of the object file starting with magic: elf,
+// pe or macho, "" if it is none of them.
+func objFormat(magic []byte) string {
+	switch {
+	case len(magic) < 4:
+		return ""
+	case bytes.HasPrefix(magic, []byte(felf.ELFMAG)):
+		return "elf"
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		return "pe"
+	}
+	switch binary.LittleEndian.Uint32(magic) {
+	case macho.Magic32, macho.Magic64, bswap(macho.Magic32), bswap(macho.Magic64):
+		return "macho"
+	}
+	return ""
+}
+
+// IsObjFile reports whether the file starting with magic, at least 4 bytes
+// of it, is in one of the formats Open reads.
+func IsObjFile(magic []byte) bool {
+	return objFormat(magic) != ""
+}
+
+// source is the file an objFile is read from.
+type source struct {
+	c io.Closer // nil if the caller closes it, see OpenReader
+	m []byte    // the file mapped read-only, nil if it is read with ReadAt
+}
+
+func (s *source) Close() error {
+	var err error
+	if s.m != nil {
+		err = munmap(s.m)
+		s.m = nil
+	}
+	if s.c == nil {
+		return err
+	}
+	if cerr := s.c.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// load points the contents of sec to the mapped file at offset off, they
+// are read on first use if the file is not mapped.
+func (s *source) load(sec *section, off uint64) {
+	if s.m == nil || sec.filesz == 0 || off > uint64(len(s.m)) || sec.filesz > uint64(len(s.m))-off {
+		return
+	}
+	sec.data = s.m[off : off+sec.filesz : off+sec.filesz]
+}
+
+func bswap(v uint32) uint32 {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return binary.LittleEndian.Uint32(b[:])
+}
+
+type elfFile struct {
+	f *felf.File
+	*source
+}
+
+func (o *elfFile) sections() []*section {
+	var secs []*section
+	for _, s := range o.f.Sections {
+		if s.Flags&felf.SHF_ALLOC == 0 {
+			continue
+		}
+		sec := &section{
+			name:     s.Name,
+			addr:     s.Addr,
+			size:     s.Size,
+			writable: s.Flags&felf.SHF_WRITE != 0,
+			r:        s,
+		}
+		if s.Type != felf.SHT_NOBITS {
+			sec.filesz = s.Size
+			if s.Flags&felf.SHF_COMPRESSED == 0 {
+				o.load(sec, s.Offset)
+			}
+		}
+		secs = append(secs, sec)
+	}
+	return secs
+}
+
+// symbol scans the symbol table for name, rather than decoding all of it
+// like debug/elf does.
+func (o *elfFile) symbol(name string) (uint64, bool) {
+	symtab := o.f.SectionByType(felf.SHT_SYMTAB)
+	if symtab == nil || int(symtab.Link) >= len(o.f.Sections) {
+		return 0, false
+	}
+	syms, err := o.data(symtab)
+	if err != nil {
+		return 0, false
+	}
+	strs, err := o.data(o.f.Sections[symtab.Link])
+	if err != nil {
+		return 0, false
+	}
+	bo := o.f.ByteOrder
+	size := felf.Sym64Size
+	if o.f.Class == felf.ELFCLASS32 {
+		size = felf.Sym32Size
+	}
+	// the first symbol is the undefined one
+	for b := syms; len(b) >= 2*size; {
+		b = b[size:]
+		var sect felf.SectionIndex
+		var value uint64
+		if size == felf.Sym64Size {
+			sect, value = felf.SectionIndex(bo.Uint16(b[6:])), bo.Uint64(b[8:])
+		} else {
+			sect, value = felf.SectionIndex(bo.Uint16(b[14:])), uint64(bo.Uint32(b[4:]))
+		}
+		off := uint64(bo.Uint32(b))
+		if sect <= felf.SHN_UNDEF || sect >= felf.SHN_LORESERVE || off+uint64(len(name)) >= uint64(len(strs)) {
+			continue
+		}
+		if strs[off+uint64(len(name))] == 0 && string(strs[off:off+uint64(len(name))]) == name {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// data returns the contents of s, from the mapped file if it is.
+func (o *elfFile) data(s *felf.Section) ([]byte, error) {
+	if o.m != nil && s.Type != felf.SHT_NOBITS && s.Flags&felf.SHF_COMPRESSED == 0 &&
+		s.Offset <= uint64(len(o.m)) && s.FileSize <= uint64(len(o.m))-s.Offset {
+		return o.m[s.Offset : s.Offset+s.FileSize], nil
+	}
+	return s.Data()
+}
+
+func (o *elfFile) arch() string {
+	le := o.f.ByteOrder == binary.LittleEndian
+	switch o.f.Machine {
+	case felf.EM_386:
+		return "386"
+	case felf.EM_X86_64:
+		return "amd64"
+	case felf.EM_ARM:
+		return "arm"
+	case felf.EM_AARCH64:
+		return "arm64"
+	case felf.EM_LOONGARCH:
+		return "loong64"
+	case felf.EM_MIPS:
+		switch {
+		case o.f.Class == felf.ELFCLASS64 && le:
+			return "mips64le"
+		case o.f.Class == felf.ELFCLASS64:
+			return "mips64"
+		case le:
+			return "mipsle"
+		}
+		return "mips"
+	case felf.EM_PPC64:
+		if le {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case felf.EM_RISCV:
+		return "riscv64"
+	case felf.EM_S390:
+		return "s390x"
+	}
+	return ""
+}
+
+func (o *elfFile) pie() bool {
+	return o.f.Type == felf.ET_DYN
+}
+
+func (o *elfFile) dwarf() (*dwarf.Data, error) {
+	return o.f.DWARF()
+}
+
+func (o *elfFile) debugSection(name string) []byte {
+	s := o.f.Section(".debug_" + name)
+	if s == nil || s.Type == felf.SHT_NOBITS {
+		return nil
+	}
+	b, _ := s.Data()
+	return b
+}
+
+func (o *elfFile) decoder() decoder {
+	if o.f.Class == felf.ELFCLASS32 {
+		return decoder{o.f.ByteOrder, 4}
+	}
+	return decoder{o.f.ByteOrder, 8}
+}
+
+type peFile struct {
+	f *pe.File
+	*source
+}
+
+func (o *peFile) imageBase() uint64 {
+	switch h := o.f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return uint64(h.ImageBase)
+	case *pe.OptionalHeader64:
+		return h.ImageBase
+	}
+	return 0
+}
+
+// sections returns the sections of the image, leaving out those discarded
+// after loading like the DWARF and the symbol table. The uninitialized data
+// is folded into the tail of .data, past its raw data.
+func (o *peFile) sections() []*section {
+	var secs []*section
+	base := o.imageBase()
+	for _, s := range o.f.Sections {
+		if s.Characteristics&pe.IMAGE_SCN_MEM_DISCARDABLE != 0 {
+			continue
+		}
+		// Size is the raw data rounded up to the file alignment.
+		size, filesz := s.VirtualSize, s.Size
+		if filesz > size {
+			filesz = size
+		}
+		if s.Characteristics&pe.IMAGE_SCN_CNT_UNINITIALIZED_DATA != 0 {
+			filesz = 0
+		}
+		sec := &section{
+			name:     s.Name,
+			addr:     base + uint64(s.VirtualAddress),
+			size:     uint64(size),
+			filesz:   uint64(filesz),
+			writable: s.Characteristics&pe.IMAGE_SCN_MEM_WRITE != 0,
+			r:        s,
+		}
+		o.load(sec, uint64(s.Offset))
+		secs = append(secs, sec)
+	}
+	return secs
+}
+
+func (o *peFile) symbol(name string) (uint64, bool) {
+	for _, s := range o.f.Symbols {
+		if s.Name != name || s.SectionNumber <= 0 || int(s.SectionNumber) > len(o.f.Sections) {
+			continue
+		}
+		sect := o.f.Sections[s.SectionNumber-1]
+		return o.imageBase() + uint64(sect.VirtualAddress) + uint64(s.Value), true
+	}
+	return 0, false
+}
+
+func (o *peFile) arch() string {
+	switch o.f.Machine {
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "386"
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		return "arm"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	}
+	return ""
+}
+
+func (o *peFile) pie() bool {
+	switch h := o.f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return h.DllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE != 0
+	case *pe.OptionalHeader64:
+		return h.DllCharacteristics&pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE != 0
+	}
+	return false
+}
+
+func (o *peFile) dwarf() (*dwarf.Data, error) {
+	return o.f.DWARF()
+}
+
+func (o *peFile) debugSection(name string) []byte {
+	s := o.f.Section(".debug_" + name)
+	if s == nil {
+		return nil
+	}
+	b, _ := s.Data()
+	// the raw data is padded to the file alignment
+	if 0 < s.VirtualSize && s.VirtualSize < uint32(len(b)) {
+		b = b[:s.VirtualSize]
+	}
+	return b
+}
+
+func (o *peFile) decoder() decoder {
+	if _, ok := o.f.OptionalHeader.(*pe.OptionalHeader32); ok {
+		return decoder{binary.LittleEndian, 4}
+	}
+	return decoder{binary.LittleEndian, 8}
+}
+
+type machoFile struct {
+	f *macho.File
+	*source
+}
+
+// section types and attributes from <mach-o/loader.h>
+const (
+	machoSectionType  = 0xff
+	machoZerofill     = 0x1
+	machoGBZerofill   = 0xc
+	machoTLVZerofill  = 0x12
+	machoAttrDebug    = 0x02000000
+	machoProtWritable = 0x2
+)
+
+func (o *machoFile) sections() []*section {
+	var secs []*section
+	for _, s := range o.f.Sections {
+		seg := o.f.Segment(s.Seg)
+		if seg == nil || seg.Maxprot == 0 || s.Flags&machoAttrDebug != 0 {
+			continue
+		}
+		sec := &section{
+			name:     s.Name,
+			addr:     s.Addr,
+			size:     s.Size,
+			filesz:   s.Size,
+			writable: seg.Prot&machoProtWritable != 0,
+			r:        s,
+		}
+		switch s.Flags & machoSectionType {
+		case machoZerofill, machoGBZerofill, machoTLVZerofill:
+			sec.filesz = 0
+		}
+		o.load(sec, uint64(s.Offset))
+		secs = append(secs, sec)
+	}
+	return secs
+}
+
+// symbol looks for name as is, as the Go linker writes it, and with the
+// underscore prefix of C symbols.
+func (o *machoFile) symbol(name string) (uint64, bool) {
+	if o.f.Symtab == nil {
+		return 0, false
+	}
+	for _, s := range o.f.Symtab.Syms {
+		if (s.Name == name || s.Name == "_"+name) && s.Sect > 0 {
+			return s.Value, true
+		}
+	}
+	return 0, false
+}
+
+func (o *machoFile) arch() string {
+	switch o.f.Cpu {
+	case macho.Cpu386:
+		return "386"
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuPpc64:
+		return "ppc64"
+	}
+	return ""
+}
+
+func (o *machoFile) pie() bool {
+	return o.f.Flags&macho.FlagPIE != 0
+}
+
+func (o *machoFile) dwarf() (*dwarf.Data, error) {
+	return o.f.DWARF()
+}
+
+func (o *machoFile) debugSection(name string) []byte {
+	s := o.f.Section("__debug_" + name)
+	if s == nil {
+		return nil
+	}
+	b, _ := s.Data()
+	return b
+}
+
+func (o *machoFile) decoder() decoder {
+	if o.f.Magic == macho.Magic32 {
+		return decoder{o.f.ByteOrder, 4}
+	}
+	return decoder{o.f.ByteOrder, 8}
+}
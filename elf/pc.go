@@ -0,0 +1,197 @@
+package elf
+
+import (
+	"io"
+	"sort"
+	"strconv"
+)
+
+// findfunctab buckets, see runtime.findfuncbucket.
+const (
+	pcBucketSize      = 4096
+	pcSubBuckets      = 16
+	findFuncBucketLen = 4 + pcSubBuckets
+)
+
+// findFuncPC returns the function containing pc, nil if there is none. The
+// findfunctab bucket of pc gives the first candidate in ftab, from which
+// ftab is binary searched.
+func (e *ELF_Info) findFuncPC(pc uint64) *_func {
+	m := e.module
+	if pc < m.minpc || pc >= m.maxpc {
+		return nil
+	}
+	lo := 0
+	x := pc - m.minpc
+	if b := e.mem(m.findfunctab + x/pcBucketSize*findFuncBucketLen); len(b) >= findFuncBucketLen {
+		i := int(e.dec.uint32(b)) + int(b[4+x%pcBucketSize/(pcBucketSize/pcSubBuckets)])
+		if i < e.nfunc() {
+			if entry, _ := e.ftab(i); entry <= pc {
+				lo = i
+			}
+		}
+	}
+	i := lo + sort.Search(e.nfunc()-lo, func(i int) bool {
+		entry, _ := e.ftab(lo + i + 1)
+		return entry > pc
+	})
+	if i >= e.nfunc() {
+		return nil
+	}
+	_, off := e.ftab(i)
+	return e.funcAt(off)
+}
+
+// pcvalueAt returns the value of the pc-value table at offset off of pctab
+// for pc in f.
+func (e *ELF_Info) pcvalueAt(f *_func, off uint32, pc uint64) (int, bool) {
+	pcv := e.getpcvaluefunc(f, func(*_func) uint32 {
+		return off
+	})
+	for _, v := range pcv {
+		if pc >= v.pc_start && pc < v.pc_end {
+			return v.value, true
+		}
+	}
+	return 0, false
+}
+
+// inlinedCall returns the i-th entry of the inlining tree at address tree.
+func (e *ELF_Info) inlinedCall(tree uint64, i int) inlinedCall {
+	if e.ver >= ver120 {
+		b := e.read(tree+uint64(i)*16, 16)
+		return inlinedCall{
+			funcID:    funcID(b[0]),
+			nameOff:   int32(e.dec.uint32(b[4:])),
+			parentPc:  int32(e.dec.uint32(b[8:])),
+			startLine: int32(e.dec.uint32(b[12:])),
+		}
+	}
+	// parent int16, funcID, _, file, line, func_, parentPc
+	b := e.read(tree+uint64(i)*20, 20)
+	return inlinedCall{
+		funcID:   funcID(b[2]),
+		nameOff:  int32(e.dec.uint32(b[12:])),
+		parentPc: int32(e.dec.uint32(b[16:])),
+	}
+}
+
+// frames returns the logical frames at pc in f, innermost first, the way
+// the runtime expands inlined calls in a traceback: the file and line of
+// a frame are those at pc, then the pc moves to the call site in the caller.
+func (e *ELF_Info) frames(f *_func, pc uint64) []Frame {
+	var frames []Frame
+	tree := e.funcdata(f, _FUNCDATA_InlTree)
+	inl := e.pcdata(f, _PCDATA_InlTreeIndex)
+	for tree != 0 && inl != 0 {
+		ix, ok := e.pcvalueAt(f, inl, pc)
+		if !ok || ix < 0 {
+			break
+		}
+		call := e.inlinedCall(tree, ix)
+		file, line := e.funcLine(f, pc)
+		frames = append(frames, Frame{toString(e.tab.funcnametab[call.nameOff:]), file, line, true})
+		pc = f.entry + uint64(call.parentPc)
+	}
+	file, line := e.funcLine(f, pc)
+	return append(frames, Frame{e.getFuncName(f), file, line, false})
+}
+
+// funcLine returns the file and line at pc in f.
+func (e *ELF_Info) funcLine(f *_func, pc uint64) (string, int) {
+	file := "?"
+	if fileno, ok := e.pcvalueAt(f, f.pcfile, pc); ok {
+		file = e.fileName(f, fileno)
+	}
+	line, _ := e.pcvalueAt(f, f.pcln, pc)
+	return file, line
+}
+
+// PrintPC prints the function, source position, sp offset, stack map index
+// and unsafe point state at each of pcs, with the calls inlined there listed
+// as separate frames.
+func (e *ELF_Info) PrintPC(out io.Writer, pcs ...uint64) error {
+	infos, err := e.PCInfos(pcs...)
+	return writeText(out, infos, err)
+}
+
+// PCInfos returns what the runtime knows at each of pcs.
+func (e *ELF_Info) PCInfos(pcs ...uint64) (infos PCInfos, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	infos = PCInfos{}
+	for _, pc := range pcs {
+		f := e.findFuncPC(pc)
+		if f == nil {
+			return nil, &PCNotFoundError{pc}
+		}
+		p := PCInfo{PC: pc, Func: e.getFuncName(f), Offset: pc - f.entry}
+		p.SP, _ = e.pcvalueAt(f, f.pcsp, pc)
+		if v, ok := e.pcvalueAt(f, e.pcdata(f, _PCDATA_StackMapIndex), pc); ok {
+			p.StackMap = &v
+		}
+		if v, ok := e.pcvalueAt(f, e.pcdata(f, _PCDATA_UnsafePoint), pc); ok {
+			if p.UnsafePoint = unsafePoint(v); p.UnsafePoint == "" {
+				p.UnsafePoint = strconv.Itoa(v)
+			}
+		}
+		p.Frames = e.frames(f, pc)
+		infos = append(infos, p)
+	}
+	return infos, nil
+}
+
+// PrintInlTree prints the calls inlined into fn: the index of the call they
+// are inlined into (-1 for fn itself), the position of the call site, its
+// offset from the entry of fn and the pc ranges of the inlined body.
+func (e *ELF_Info) PrintInlTree(out io.Writer, fn string) error {
+	t, err := e.InlTree(fn)
+	return writeText(out, t, err)
+}
+
+// InlTree returns the calls inlined into fn.
+func (e *ELF_Info) InlTree(fn string) (t *InlTree, err error) {
+	defer catch(&err)
+	f, err := e.lookupFunc(fn)
+	if err != nil {
+		return nil, err
+	}
+	t = &InlTree{Func: fn, File: e.func_file(f), Calls: []InlCall{}}
+	tree := e.funcdata(f, _FUNCDATA_InlTree)
+	if tree == 0 {
+		return t, nil
+	}
+	pcv := e.getpcvaluefunc(f, func(f *_func) uint32 {
+		return e.pcdata(f, _PCDATA_InlTreeIndex)
+	})
+	// the tree is as large as the largest index referring to it
+	n := 0
+	for _, v := range pcv {
+		if v.value >= n {
+			n = v.value + 1
+		}
+	}
+	for i := 0; i < n; i++ {
+		call := e.inlinedCall(tree, i)
+		pc := f.entry + uint64(call.parentPc)
+		c := InlCall{
+			Index:    i,
+			Func:     toString(e.tab.funcnametab[call.nameOff:]),
+			Parent:   -1,
+			ParentPC: call.parentPc,
+		}
+		if v, ok := e.pcvalueAt(f, e.pcdata(f, _PCDATA_InlTreeIndex), pc); ok {
+			c.Parent = v
+		}
+		c.File, c.Line = e.funcLine(f, pc)
+		for _, v := range pcv {
+			if v.value == i {
+				c.Ranges = append(c.Ranges, PCRange{v.pc_start, v.pc_end})
+			}
+		}
+		t.Calls = append(t.Calls, c)
+	}
+	return t, nil
+}
@@ -0,0 +1,65 @@
+package elf
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Proc is a running process of a Go binary, read through /proc on Linux
+// without stopping it: what is read may change meanwhile.
+type Proc struct {
+	pid  int
+	mem  *os.File
+	auxv []byte // the auxiliary vector, in the words of the process
+}
+
+// OpenProc opens the memory of process pid.
+func OpenProc(pid int) (p *Proc, err error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	p = &Proc{pid: pid}
+	if p.auxv, err = os.ReadFile(dir + "/auxv"); err != nil {
+		return nil, err
+	}
+	if p.mem, err = os.Open(dir + "/mem"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close closes the memory of the process.
+func (p *Proc) Close() error {
+	return p.mem.Close()
+}
+
+// Exe returns the path of the binary the process runs, which can be opened
+// even if it was deleted.
+func (p *Proc) Exe() string {
+	return "/proc/" + strconv.Itoa(p.pid) + "/exe"
+}
+
+// ReadAt reads the memory of the process at addr.
+func (p *Proc) ReadAt(b []byte, addr int64) (int, error) {
+	return p.mem.ReadAt(b, addr)
+}
+
+// Process returns the process p, e being its binary. The threads are not
+// known, so the stacks of the goroutines running are not either. The bias
+// is that of the entry point, AT_ENTRY in the auxiliary vector, as for core
+// files: it is found even if the path of the binary is not that of the
+// process, e.g. in another mount namespace.
+func (p *Proc) Process(e *ELF_Info) (*Process, error) {
+	pr := &Process{Mem: p}
+	o, ok := e.obj.(*elfFile)
+	if !ok {
+		return pr, nil
+	}
+	w := e.dec.ptrSize
+	for b := p.auxv; len(b) >= 2*w; b = b[2*w:] {
+		if e.dec.uintptr(b) == atEntry {
+			pr.Bias = e.dec.uintptr(b[w:]) - o.f.Entry
+			return pr, nil
+		}
+	}
+	return nil, fmt.Errorf("process %d: no entry point in its auxiliary vector", p.pid)
+}
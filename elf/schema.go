@@ -0,0 +1,371 @@
+package elf
+
+// The types below are the data model of the dumps, returned by the methods
+// of ELF_Info the Print* methods render as text. They are written as is by
+// gobjdump --format json, hence the json tags.
+
+// SchemaVersion is the version of the data model. It is incremented when a
+// field is removed or changes meaning, new fields keep the version.
+const SchemaVersion = 1
+
+// Field is a named value, e.g. a field of the moduledata.
+type Field struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// ModuleData is the moduledata of the binary, with its fields in the order
+// of the layout. Slices and strings are given by the address of their data.
+type ModuleData struct {
+	Fields []Field `json:"fields"`
+}
+
+// File is a source file with the functions defined in it.
+type File struct {
+	Name  string   `json:"name"`
+	Funcs []string `json:"funcs"`
+}
+
+// Files lists the source files in the order their first function appears
+// in the function table.
+type Files []File
+
+// TypeRef refers to a type descriptor.
+type TypeRef struct {
+	Addr uint64 `json:"addr"`
+	Name string `json:"name"`
+}
+
+// Type is a type descriptor, with its contents if they were asked for.
+type Type struct {
+	Addr uint64    `json:"addr"`
+	Name string    `json:"name"`
+	Desc *TypeDesc `json:"desc,omitempty"`
+}
+
+// Types lists types sorted by address.
+type Types []Type
+
+// TypeDesc is the contents of a type descriptor. Only the fields of the kind
+// specific part matching Kind are set.
+type TypeDesc struct {
+	Kind       string   `json:"kind"`
+	Size       uint64   `json:"size"`
+	Align      uint8    `json:"align"`
+	FieldAlign uint8    `json:"fieldAlign"`
+	PtrData    uint64   `json:"ptrdata"`
+	TFlag      string   `json:"tflag"`
+	PkgPath    string   `json:"pkgpath,omitempty"`
+	PtrToThis  *TypeRef `json:"ptrToThis,omitempty"`
+
+	Elem     *TypeRef      `json:"elem,omitempty"`     // array, chan, map, ptr, slice
+	Slice    *TypeRef      `json:"slice,omitempty"`    // array
+	Len      uint64        `json:"len,omitempty"`      // array
+	Dir      string        `json:"dir,omitempty"`      // chan
+	In       []TypeRef     `json:"in,omitempty"`       // func
+	Out      []TypeRef     `json:"out,omitempty"`      // func
+	Variadic bool          `json:"variadic,omitempty"` // func
+	Methods  []IMethod     `json:"methods,omitempty"`  // interface
+	Key      *TypeRef      `json:"key,omitempty"`      // map
+	Bucket   *TypeRef      `json:"bucket,omitempty"`   // map, the buckets or groups
+	MapSizes []Field       `json:"mapSizes,omitempty"` // map, sizes, offsets and flags
+	Fields   []StructField `json:"fields,omitempty"`   // struct
+}
+
+// IMethod is a method of an interface type.
+type IMethod struct {
+	Name string  `json:"name"`
+	Type TypeRef `json:"type"`
+}
+
+// StructField is a field of a struct type.
+type StructField struct {
+	Name     string  `json:"name"`
+	Type     TypeRef `json:"type"`
+	Offset   uint64  `json:"offset"`
+	Embedded bool    `json:"embedded,omitempty"`
+	Tag      string  `json:"tag,omitempty"`
+}
+
+// Struct is a struct type with its fields.
+type Struct struct {
+	Addr   uint64        `json:"addr"`
+	Name   string        `json:"name"`
+	Fields []StructField `json:"fields"`
+}
+
+// Structs lists struct types sorted by address.
+type Structs []Struct
+
+// FuncRef refers to the function at Addr. Name is empty if no function
+// starts there.
+type FuncRef struct {
+	Addr uint64 `json:"addr"`
+	Name string `json:"name,omitempty"`
+}
+
+// MethodSet is the method set of a type, from its uncommontype if it has
+// one.
+type MethodSet struct {
+	Addr     uint64   `json:"addr"`
+	Name     string   `json:"name"`
+	Uncommon bool     `json:"uncommon"`
+	PkgPath  string   `json:"pkgpath,omitempty"`
+	Exported int      `json:"exported"`
+	Methods  []Method `json:"methods"`
+}
+
+// MethodSets lists method sets sorted by the address of their type.
+type MethodSets []MethodSet
+
+// Method is a method of a named type. Type, IFn and TFn are nil if the
+// linker eliminated them.
+type Method struct {
+	Name string   `json:"name"`
+	Type *TypeRef `json:"type"`
+	IFn  *FuncRef `json:"ifn"` // called through interfaces
+	TFn  *FuncRef `json:"tfn"` // called directly
+}
+
+// Itab is an interface table, the functions implementing the methods of
+// Interface for Type. It has a single function at address 0 if Type does
+// not implement Interface.
+type Itab struct {
+	Addr      uint64       `json:"addr"`
+	Type      TypeRef      `json:"type"`
+	Interface TypeRef      `json:"interface"`
+	Methods   []ItabMethod `json:"methods"`
+}
+
+// ItabMethod is a method of an itab and the function implementing it.
+type ItabMethod struct {
+	Name string  `json:"name"`
+	Func FuncRef `json:"func"`
+}
+
+// Itabs lists itabs in the order of the binary.
+type Itabs []Itab
+
+// BuildInfo is the Go version, modules and build settings the binary was
+// built with, see runtime/debug.BuildInfo.
+type BuildInfo struct {
+	GoVersion string         `json:"goVersion"`
+	Path      string         `json:"path,omitempty"`
+	Main      BuildModule    `json:"main"`
+	Deps      []BuildModule  `json:"deps,omitempty"`
+	Settings  []BuildSetting `json:"settings,omitempty"`
+}
+
+// BuildModule is a module the binary was built from.
+type BuildModule struct {
+	Path    string       `json:"path"`
+	Version string       `json:"version,omitempty"`
+	Sum     string       `json:"sum,omitempty"`
+	Replace *BuildModule `json:"replace,omitempty"`
+}
+
+// BuildSetting is a key=value build setting, e.g. GOARCH or vcs.revision.
+type BuildSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PCValues is a pc-value table of a function: pcsp, pcln or unsafepoint.
+type PCValues struct {
+	Func   string    `json:"func"`
+	File   string    `json:"file"`
+	Table  string    `json:"table"`
+	Values []PCValue `json:"values"`
+}
+
+// PCValue is the value of a pc-value table in [Start, End).
+type PCValue struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Value int    `json:"value"`
+	Name  string `json:"name,omitempty"` // of unsafepoint values
+}
+
+// PointerMap is the argument or local pointer map of a function, the bit
+// vector of live pointer words in each range of pcs. Addr is 0 if the
+// function has none.
+type PointerMap struct {
+	Func   string            `json:"func"`
+	File   string            `json:"file"`
+	Addr   uint64            `json:"addr"`
+	Ranges []PointerMapRange `json:"ranges"`
+}
+
+// PointerMapRange is the bit vector of the stack map index Index, in effect
+// in [Start, End). Bits lists its bytes in binary, lowest word first. Vars
+// names the set bits with the variables the DWARF of the binary places in
+// their words, if it has any.
+type PointerMapRange struct {
+	Start uint64          `json:"start"`
+	End   uint64          `json:"end"`
+	Index int             `json:"index"`
+	Bits  string          `json:"bits"`
+	Vars  []PointerMapVar `json:"vars,omitempty"`
+}
+
+// PointerMapVar is the parameter or local variable Name of type Type which
+// word at Off holds bit Bit of a pointer map, at FP from the sp of the
+// caller.
+type PointerMapVar struct {
+	Bit  int    `json:"bit"`
+	FP   int64  `json:"fp"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Off  int64  `json:"off"`
+}
+
+// StackObjects is the stack objects of a function, addressed in its frame.
+// Addr is 0 if the function has none.
+type StackObjects struct {
+	Func    string        `json:"func"`
+	File    string        `json:"file"`
+	Addr    uint64        `json:"addr"`
+	Objects []StackObject `json:"objects"`
+}
+
+// StackObject is an object on the stack, from varp if Off is negative or
+// else from argp. GCBits are the pointer bits at GCData, in binary.
+type StackObject struct {
+	Off     int32  `json:"off"`
+	Size    int32  `json:"size"`
+	PtrData int32  `json:"ptrdata"`
+	GCData  uint64 `json:"gcdata"`
+	GCBits  string `json:"gcbits,omitempty"`
+}
+
+// InlTree is the calls inlined into a function.
+type InlTree struct {
+	Func  string    `json:"func"`
+	File  string    `json:"file"`
+	Calls []InlCall `json:"calls"`
+}
+
+// InlCall is an inlined call: Parent is the index of the call it is inlined
+// into, -1 for the function itself, File and Line locate the call site at
+// ParentPC from the entry of the function. Ranges are where its body is.
+type InlCall struct {
+	Index    int       `json:"index"`
+	Func     string    `json:"func"`
+	Parent   int       `json:"parent"`
+	File     string    `json:"file"`
+	Line     int       `json:"line"`
+	ParentPC int32     `json:"parentPc"`
+	Ranges   []PCRange `json:"ranges"`
+}
+
+// PCRange is the range of pcs [Start, End).
+type PCRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// PCInfo is what the runtime knows at a pc. StackMap is nil and UnsafePoint
+// empty if the function has no such table at PC.
+type PCInfo struct {
+	PC          uint64  `json:"pc"`
+	Func        string  `json:"func"`
+	Offset      uint64  `json:"offset"`
+	SP          int     `json:"sp"`
+	StackMap    *int    `json:"stackmap"`
+	UnsafePoint string  `json:"unsafepoint,omitempty"`
+	Frames      []Frame `json:"frames"`
+}
+
+// PCInfos lists PCInfo in the order the pcs were asked for.
+type PCInfos []PCInfo
+
+// Frame is a logical frame at a pc, one of the calls inlined there or the
+// function itself.
+type Frame struct {
+	Func    string `json:"func"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Inlined bool   `json:"inlined"`
+}
+
+// Binary identifies a Go binary, see ELF_Info.Summary. Path and Error are
+// left to the caller, Error being set if the binary could not be decoded.
+type Binary struct {
+	Path      string `json:"path"`
+	GoVersion string `json:"goVersion,omitempty"`
+	Main      string `json:"main,omitempty"` // path of the main module, else of the main package
+	Arch      string `json:"arch,omitempty"` // GOARCH of the target
+	Stripped  bool   `json:"stripped"`       // of its symbol table
+	PIE       bool   `json:"pie"`
+	Layout    string `json:"layout,omitempty"` // of the runtime data, see Module.Layout
+	Error     string `json:"error,omitempty"`
+}
+
+// Binaries lists binaries in the order they were found.
+type Binaries []Binary
+
+// StackFrame is a physical frame of a goroutine stack, with the calls
+// inlined at its pc. FP is the value of sp in the caller.
+type StackFrame struct {
+	PC     uint64  `json:"pc"`
+	SP     uint64  `json:"sp"`
+	FP     uint64  `json:"fp"`
+	Func   string  `json:"func"`
+	Offset uint64  `json:"offset"` // of PC from the entry of Func
+	Frames []Frame `json:"frames"`
+}
+
+// Goroutine is a goroutine of a process, see ELF_Info.Goroutines.
+type Goroutine struct {
+	ID         uint64       `json:"id"`
+	Status     string       `json:"status"`
+	WaitReason string       `json:"waitReason,omitempty"`
+	Thread     int          `json:"thread,omitempty"` // running it
+	Parent     uint64       `json:"parent,omitempty"` // go1.21 and later
+	CreatedBy  *StackFrame  `json:"createdBy,omitempty"`
+	Stack      []StackFrame `json:"stack"`
+	Error      string       `json:"error,omitempty"` // why Stack stops short
+}
+
+// Goroutines lists goroutines in the order of runtime.allgs.
+type Goroutines []Goroutine
+
+// FrameLayout is the frame of a function at a pc, see
+// ELF_Info.FrameLayout. Size is the pcsp value at PC and StackMap the index
+// of the pointer maps in effect there. Slots go from the arguments at the
+// top of the frame down to sp.
+type FrameLayout struct {
+	Func     string      `json:"func"`
+	File     string      `json:"file"`
+	PC       uint64      `json:"pc"`
+	Offset   uint64      `json:"offset"` // of PC from the entry of Func
+	Size     int         `json:"size"`
+	StackMap int         `json:"stackmap"`
+	Slots    []FrameSlot `json:"slots"`
+}
+
+// FrameSlot is a word of a frame at SP from sp and FP from fp, the sp of
+// the caller. Kind is arg, caller (the words between fp and the arguments),
+// return address, frame pointer (of the caller, saved) or local. Pointer
+// is set if the pointer maps say the word is a live pointer. Object is the
+// offset of the stack object holding the word, if any, and ObjectPointer
+// set if its type says the word is a pointer.
+type FrameSlot struct {
+	SP            int64     `json:"sp"`
+	FP            int64     `json:"fp"`
+	Kind          string    `json:"kind"`
+	Pointer       bool      `json:"pointer"`
+	Object        *int32    `json:"object,omitempty"`
+	ObjectPointer bool      `json:"objectPointer,omitempty"`
+	Arg           *FrameArg `json:"arg,omitempty"`
+}
+
+// FrameArg is the argument word, or the part of it, at Off from the
+// arguments for Size bytes, the Index-th listed for tracebacks. Those passed
+// in registers are spilled there, Live telling whether they are at the pc.
+type FrameArg struct {
+	Index int  `json:"index"`
+	Off   int  `json:"off"`
+	Size  int  `json:"size"`
+	Live  bool `json:"live"`
+}
@@ -0,0 +1,113 @@
+package elf
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FuncSelector selects functions by name for SelectFuncs.
+type FuncSelector struct {
+	// Patterns are exact function names, globs where * matches any string
+	// and ? any character, or regular expressions between slashes, e.g.
+	// /^net\/http\..*Serve/. A name of a function is always taken as is.
+	Patterns []string
+	// Packages restricts the functions to those of these packages, e.g.
+	// net/http. Without Patterns, all their functions are selected.
+	Packages []string
+	// All selects all the functions, of Packages if there are any.
+	All bool
+}
+
+// SelectFuncs returns the names of the functions selected by s, in the
+// order of the function table. A pattern or package matching no function
+// is reported with a FuncNotFoundError.
+func (e *ELF_Info) SelectFuncs(s FuncSelector) (names []string, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	x := e.funcIndex()
+	matchers := make([]func(string) bool, len(s.Patterns))
+	for i, p := range s.Patterns {
+		_, exact := x.byName[p]
+		if matchers[i], err = funcMatcher(p, exact); err != nil {
+			return nil, err
+		}
+	}
+	pkgs := make(map[string]bool, len(s.Packages))
+	for _, p := range s.Packages {
+		pkgs[p] = false
+	}
+	matched := make([]bool, len(s.Patterns))
+	seen := make(map[string]bool)
+	for _, name := range x.names {
+		if len(pkgs) > 0 {
+			if _, ok := pkgs[funcPackage(name)]; !ok {
+				continue
+			}
+			pkgs[funcPackage(name)] = true
+		}
+		ok := s.All || len(s.Patterns) == 0 && len(pkgs) > 0
+		for i, m := range matchers {
+			if m(name) {
+				ok, matched[i] = true, true
+			}
+		}
+		if ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for i, p := range s.Patterns {
+		if !matched[i] {
+			return nil, &FuncNotFoundError{p}
+		}
+	}
+	for _, p := range s.Packages {
+		if !pkgs[p] {
+			return nil, &FuncNotFoundError{p + ".*"}
+		}
+	}
+	return names, nil
+}
+
+// funcMatcher returns the matcher of the pattern p, see FuncSelector. exact
+// is set if p is the name of a function.
+func funcMatcher(p string, exact bool) (func(string) bool, error) {
+	var re *regexp.Regexp
+	var err error
+	switch {
+	case exact || !strings.ContainsAny(p, "*?") && !isRegexp(p):
+		return func(name string) bool {
+			return name == p
+		}, nil
+	case isRegexp(p):
+		re, err = regexp.Compile(p[1 : len(p)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid function pattern %s: %v", p, err)
+		}
+	default:
+		re = regexp.MustCompile("^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(p)) + "$")
+	}
+	return re.MatchString, nil
+}
+
+func isRegexp(p string) bool {
+	return len(p) >= 2 && p[0] == '/' && p[len(p)-1] == '/'
+}
+
+// funcPackage returns the package path of the function named name, the
+// part up to the first dot after the last slash, not counting those in
+// type arguments.
+func funcPackage(name string) string {
+	s := name
+	if b := strings.IndexByte(s, '['); b >= 0 {
+		s = s[:b]
+	}
+	i := strings.LastIndexByte(s, '/') + 1
+	if j := strings.IndexByte(s[i:], '.'); j >= 0 {
+		return s[:i+j]
+	}
+	return s
+}
@@ -0,0 +1,81 @@
+package elf
+
+import "fmt"
+
+// loadStripped locates the moduledata in binaries without a symbol table,
+// e.g. built with -ldflags=-s: it finds the pclntab, then a moduledata
+// referring to it which is consistent with its header.
+func (e *ELF_Info) loadStripped() error {
+	hdrs := e.pclntabs()
+	if len(hdrs) == 0 {
+		return fmt.Errorf("%w: neither %s nor %s found", ErrNotGoBinary, FIRST_MOD_SYM, SEC_PCLN)
+	}
+	var err error
+	for _, hdr := range hdrs {
+		for _, addr := range e.moduleCandidates(hdr) {
+			if err = e.loadModule(addr); err == nil {
+				return nil
+			}
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: no moduledata refers to the pclntab at %#x", ErrNotGoBinary, hdrs[0])
+	}
+	return err
+}
+
+// pclntabs returns the address of the pclntab, from its section (ELF, Mach-O)
+// or symbol (PE) or else by looking for a pcHeader in the read-only data.
+func (e *ELF_Info) pclntabs() []uint64 {
+	for _, s := range e.secs {
+		if s.name == SEC_PCLN || s.name == SEC_MACHO_PCLN {
+			return []uint64{s.addr}
+		}
+	}
+	if addr, ok := e.obj.symbol(PCLNTAB_SYM); ok {
+		return []uint64{addr}
+	}
+	var hdrs []uint64
+	for _, s := range e.secs {
+		if s.writable {
+			continue
+		}
+		b := s.contents()
+		for off := 0; off+8 <= len(b); off += e.dec.ptrSize {
+			if isPCHeader(e.dec.uint32(b[off:]), b[off+4:off+8], e.dec.ptrSize) {
+				hdrs = append(hdrs, s.addr+uint64(off))
+			}
+		}
+	}
+	return hdrs
+}
+
+// isPCHeader reports whether magic and the bytes following it look like the
+// start of a pcHeader.
+func isPCHeader(magic uint32, b []byte, ptrSize int) bool {
+	switch magic {
+	case go12magic, go116magic, go118magic, go120magic:
+	default:
+		return false
+	}
+	// pad1, pad2, minLC, ptrSize
+	return b[0] == 0 && b[1] == 0 && (b[2] == 1 || b[2] == 2 || b[2] == 4) && int(b[3]) == ptrSize
+}
+
+// moduleCandidates returns the addresses of the pointers to hdr in the
+// writable data, the moduledata starts with one.
+func (e *ELF_Info) moduleCandidates(hdr uint64) []uint64 {
+	var addrs []uint64
+	for _, s := range e.secs {
+		if !s.writable {
+			continue
+		}
+		b := s.contents()
+		for off := 0; off+e.dec.ptrSize <= len(b); off += e.dec.ptrSize {
+			if e.dec.uintptr(b[off:]) == hdr {
+				addrs = append(addrs, s.addr+uint64(off))
+			}
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,52 @@
+package elf
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrintStructTags prints the struct types with their fields and tags, like
+// they are declared. Only structs with tagged fields are printed unless all
+// is set.
+func (e *ELF_Info) PrintStructTags(out io.Writer, all bool) error {
+	structs, err := e.StructTags(all)
+	return writeText(out, structs, err)
+}
+
+// StructTags returns the struct types with their fields, only those with
+// tagged fields unless all is set.
+func (e *ELF_Info) StructTags(all bool) (structs Structs, err error) {
+	defer catch(&err)
+	types, err := e.allTypes()
+	if err != nil {
+		return nil, err
+	}
+	structs = Structs{}
+	for _, t := range types {
+		d := e.decodeType(t)
+		if d.struct_ == nil {
+			continue
+		}
+		fields := e.structFieldsOf(d.struct_)
+		tagged := false
+		for _, f := range fields {
+			if f.Tag != "" {
+				tagged = true
+			}
+		}
+		if !tagged && !all {
+			continue
+		}
+		structs = append(structs, Struct{Addr: t, Name: e.typeName(t), Fields: fields})
+	}
+	return structs, nil
+}
+
+// quoteTag quotes tag like in the source, as a raw string if possible.
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") || !strconv.CanBackquote(tag) {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
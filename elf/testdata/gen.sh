@@ -0,0 +1,19 @@
+#!/bin/sh
+# gen.sh builds layout/main.go with one Go release per layout of the runtime
+# data into the fixtures of TestLayouts, e.g.
+#
+#	./gen.sh go1.20.14 go1.21.13
+#
+# The toolchains are fetched through GOTOOLCHAIN. The binaries are built
+# without DWARF and their .text is zeroed, only the runtime data is read.
+set -e
+cd "$(dirname "$0")"
+for v in "$@"; do
+	out=$(mktemp)
+	GOTOOLCHAIN=$v GOOS=linux GOARCH=amd64 CGO_ENABLED=0 \
+		go build -trimpath -ldflags=-w -o "$out" layout/main.go
+	text=$(readelf -SW "$out" | sed 's/^ *\[ *[0-9]*\]//' | awk '$1 == ".text" { print $4, $5 }')
+	dd if=/dev/zero of="$out" bs=1 seek=$((0x${text% *})) count=$((0x${text#* })) conv=notrunc 2>/dev/null
+	gzip -9 -n -c "$out" >"layout-$v.gz"
+	rm "$out"
+done
@@ -0,0 +1,33 @@
+// Command layout is built by gen.sh with old Go releases into the fixture
+// binaries of TestLayouts.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type Config struct {
+	Name string `json:"name"`
+	Port int    `json:"port,omitempty"`
+}
+
+type Shape interface {
+	Area() float64
+	Name() string
+}
+
+type Square struct{ side float64 }
+
+func (s *Square) Area() float64 { return s.side * s.side }
+func (s *Square) Name() string  { return "square" }
+
+//go:noinline
+func describe(s Shape) string {
+	return fmt.Sprintf("%s %.1f", s.Name(), s.Area())
+}
+
+func main() {
+	c := map[string]Config{"a": {Name: "a", Port: len(os.Args)}}
+	fmt.Println(c, describe(&Square{float64(len(os.Args))}))
+}
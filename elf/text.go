@@ -0,0 +1,392 @@
+package elf
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+)
+
+// textWriter is implemented by the types of the data model, WriteText
+// renders them as text. This is what the Print* methods write.
+type textWriter interface {
+	WriteText(out io.Writer)
+}
+
+// writeText renders v, as returned with err by a getter, unless err is set.
+func writeText(out io.Writer, v textWriter, err error) error {
+	if err != nil {
+		return err
+	}
+	v.WriteText(out)
+	return nil
+}
+
+func (t *TypeRef) String() string {
+	if t == nil || t.Addr == 0 {
+		return "nil"
+	}
+	return fmt.Sprintf("%s (%#x)", t.Name, t.Addr)
+}
+
+func (f *FuncRef) String() string {
+	if f == nil {
+		return "-1 (eliminated)"
+	}
+	if f.Name == "" {
+		return fmt.Sprintf("%#x ?", f.Addr)
+	}
+	return fmt.Sprintf("%#x %s", f.Addr, f.Name)
+}
+
+// WriteText prints the fields of m in the order of its layout.
+func (m *ModuleData) WriteText(out io.Writer) {
+	fmt.Fprintln(out, "moduledata {")
+	for _, f := range m.Fields {
+		fmt.Fprintf(out, "    %15s: %#x\n", f.Name, f.Value)
+	}
+	fmt.Fprintln(out, "}")
+}
+
+func (fs Files) WriteText(out io.Writer) {
+	for _, f := range fs {
+		fmt.Fprintln(out, f.Name+":")
+		for _, fn := range f.Funcs {
+			fmt.Fprintf(out, "    %s\n", fn)
+		}
+	}
+}
+
+func (ts Types) WriteText(out io.Writer) {
+	for _, t := range ts {
+		fmt.Fprintf(out, "%#x: %s\n", t.Addr, t.Name)
+		if t.Desc != nil {
+			t.Desc.WriteText(out)
+		}
+	}
+}
+
+func (d *TypeDesc) WriteText(out io.Writer) {
+	fmt.Fprintf(out, "    kind: %s, size: %#x, align: %d, fieldAlign: %d, ptrdata: %#x, tflag: %s\n",
+		d.Kind, d.Size, d.Align, d.FieldAlign, d.PtrData, d.TFlag)
+	if d.PkgPath != "" {
+		fmt.Fprintf(out, "    pkgpath: %s\n", d.PkgPath)
+	}
+	if d.PtrToThis != nil {
+		fmt.Fprintf(out, "    ptrToThis: %s\n", d.PtrToThis)
+	}
+	switch d.Kind {
+	case "ptr", "slice":
+		if d.Elem != nil {
+			fmt.Fprintf(out, "    elem: %s\n", d.Elem)
+		}
+	case "array":
+		fmt.Fprintf(out, "    elem: %s\n", d.Elem)
+		fmt.Fprintf(out, "    slice: %s\n", d.Slice)
+		fmt.Fprintf(out, "    len: %d\n", d.Len)
+	case "chan":
+		fmt.Fprintf(out, "    elem: %s\n", d.Elem)
+		fmt.Fprintf(out, "    dir: %s\n", d.Dir)
+	case "func":
+		for i := range d.In {
+			fmt.Fprintf(out, "    in: %s\n", &d.In[i])
+		}
+		for i := range d.Out {
+			fmt.Fprintf(out, "    out: %s\n", &d.Out[i])
+		}
+		if d.Variadic {
+			fmt.Fprintln(out, "    variadic")
+		}
+	case "interface":
+		for _, m := range d.Methods {
+			fmt.Fprintf(out, "    method %s: %s\n", m.Name, &m.Type)
+		}
+	case "map":
+		fmt.Fprintf(out, "    key: %s\n", d.Key)
+		fmt.Fprintf(out, "    elem: %s\n", d.Elem)
+		fmt.Fprintf(out, "    bucket: %s\n", d.Bucket)
+		for _, f := range d.MapSizes {
+			fmt.Fprintf(out, "    %s: %#x\n", f.Name, f.Value)
+		}
+	case "struct":
+		for _, f := range d.Fields {
+			embedded := ""
+			if f.Embedded {
+				embedded = ", embedded"
+			}
+			fmt.Fprintf(out, "    field %s at %#x: %s%s\n", f.Name, f.Offset, &f.Type, embedded)
+		}
+	}
+}
+
+// WriteText prints the structs with their fields like they are declared.
+func (ss Structs) WriteText(out io.Writer) {
+	for _, s := range ss {
+		fmt.Fprintf(out, "%#x: %s\n", s.Addr, s.Name)
+		for _, f := range s.Fields {
+			line := f.Name + " " + f.Type.Name
+			if f.Embedded {
+				line = f.Type.Name
+			}
+			if f.Tag != "" {
+				line += " " + quoteTag(f.Tag)
+			}
+			fmt.Fprintln(out, "    "+line)
+		}
+	}
+}
+
+func (ms MethodSets) WriteText(out io.Writer) {
+	for _, s := range ms {
+		fmt.Fprintf(out, "%#x: %s\n", s.Addr, s.Name)
+		if !s.Uncommon {
+			fmt.Fprintln(out, "    methods: 0")
+			continue
+		}
+		fmt.Fprintf(out, "    pkgpath: %s\n", s.PkgPath)
+		fmt.Fprintf(out, "    methods: %d, exported: %d\n", len(s.Methods), s.Exported)
+		for _, m := range s.Methods {
+			mtyp := "-1 (eliminated)"
+			if m.Type != nil {
+				mtyp = m.Type.Name
+			}
+			fmt.Fprintf(out, "    %s: %s\n", m.Name, mtyp)
+			fmt.Fprintf(out, "        ifn: %s\n", m.IFn)
+			fmt.Fprintf(out, "        tfn: %s\n", m.TFn)
+		}
+	}
+}
+
+// WriteText prints the itabs as concrete type -> interface type.
+func (is Itabs) WriteText(out io.Writer) {
+	for _, it := range is {
+		fmt.Fprintf(out, "%#x: %s -> %s\n", it.Addr, it.Type.Name, it.Interface.Name)
+		for _, m := range it.Methods {
+			fmt.Fprintf(out, "    %s: %s\n", m.Name, &m.Func)
+		}
+	}
+}
+
+// WriteText prints the build info in the format of go version -m.
+func (b *BuildInfo) WriteText(out io.Writer) {
+	module := func(m *BuildModule) *debug.Module {
+		dm := &debug.Module{Path: m.Path, Version: m.Version, Sum: m.Sum}
+		if m.Replace != nil {
+			dm.Replace = &debug.Module{Path: m.Replace.Path, Version: m.Replace.Version, Sum: m.Replace.Sum}
+		}
+		return dm
+	}
+	bi := &debug.BuildInfo{GoVersion: b.GoVersion, Path: b.Path, Main: *module(&b.Main)}
+	for i := range b.Deps {
+		bi.Deps = append(bi.Deps, module(&b.Deps[i]))
+	}
+	for _, s := range b.Settings {
+		bi.Settings = append(bi.Settings, debug.BuildSetting{Key: s.Key, Value: s.Value})
+	}
+	fmt.Fprint(out, strings.TrimSuffix(bi.String(), "\n")+"\n")
+}
+
+func writeFuncHeader(out io.Writer, fn, file string) {
+	fmt.Fprintln(out, fn+"("+file+"):")
+}
+
+func (t *PCValues) WriteText(out io.Writer) {
+	writeFuncHeader(out, t.Func, t.File)
+	for _, v := range t.Values {
+		switch {
+		case t.Table == "pcln":
+			fmt.Fprintf(out, "    %#x-->%#x: %d\n", v.Start, v.End, v.Value)
+		case v.Name != "":
+			fmt.Fprintf(out, "    %#x-->%#x: %s\n", v.Start, v.End, v.Name)
+		default:
+			fmt.Fprintf(out, "    %#x-->%#x: %#x\n", v.Start, v.End, v.Value)
+		}
+	}
+}
+
+func (m *PointerMap) WriteText(out io.Writer) {
+	writeFuncHeader(out, m.Func, m.File)
+	fmt.Fprintf(out, "%#x:\n", m.Addr)
+	for _, r := range m.Ranges {
+		fmt.Fprintf(out, "    %#x-->%#x:   %s\n", r.Start, r.End, r.Bits)
+		for _, v := range r.Vars {
+			name := v.Name
+			if v.Off != 0 {
+				name += fmt.Sprintf("+%d", v.Off)
+			}
+			fmt.Fprintf(out, "        [%d] fp%+d: %s %s\n", v.Bit, v.FP, name, v.Type)
+		}
+	}
+}
+
+func (s *StackObjects) WriteText(out io.Writer) {
+	writeFuncHeader(out, s.Func, s.File)
+	fmt.Fprintf(out, "%#x:\n", s.Addr)
+	for _, o := range s.Objects {
+		fmt.Fprintln(out, "stackObjectRecord {")
+		fmt.Fprintf(out, "    %15s: %#x\n", "off", o.Off)
+		fmt.Fprintf(out, "    %15s: %#x\n", "size", o.Size)
+		fmt.Fprintf(out, "    %15s: %#x\n", "_ptrdata", o.PtrData)
+		fmt.Fprintf(out, "    %15s: %#x\n", "gcdata", o.GCData)
+		fmt.Fprintln(out, "}")
+		if o.GCBits != "" {
+			fmt.Fprintln(out, "gcbits:  "+o.GCBits)
+		}
+	}
+}
+
+func (t *InlTree) WriteText(out io.Writer) {
+	writeFuncHeader(out, t.Func, t.File)
+	for _, c := range t.Calls {
+		fmt.Fprintf(out, "    [%d] %s, parent: %d, call: %s:%d, parentPc: %#x\n",
+			c.Index, c.Func, c.Parent, c.File, c.Line, c.ParentPC)
+		for _, r := range c.Ranges {
+			fmt.Fprintf(out, "        %#x-->%#x\n", r.Start, r.End)
+		}
+	}
+}
+
+func (ps PCInfos) WriteText(out io.Writer) {
+	for _, p := range ps {
+		fmt.Fprintf(out, "%#x: %s+%#x\n", p.PC, p.Func, p.Offset)
+		fmt.Fprintf(out, "    pcsp: %#x\n", p.SP)
+		if p.StackMap != nil {
+			fmt.Fprintf(out, "    stackmap: %d\n", *p.StackMap)
+		} else {
+			fmt.Fprintln(out, "    stackmap: -")
+		}
+		if p.UnsafePoint != "" {
+			fmt.Fprintf(out, "    unsafepoint: %s\n", p.UnsafePoint)
+		} else {
+			fmt.Fprintln(out, "    unsafepoint: -")
+		}
+		for _, fr := range p.Frames {
+			if fr.Inlined {
+				fmt.Fprintf(out, "    %s(...) inlined\n", fr.Func)
+			} else {
+				fmt.Fprintf(out, "    %s()\n", fr.Func)
+			}
+			fmt.Fprintf(out, "        %s:%d\n", fr.File, fr.Line)
+		}
+	}
+}
+
+func (b *Binary) WriteText(out io.Writer) {
+	fmt.Fprintln(out, b.row())
+}
+
+// WriteText prints bs as a table, one binary per row.
+func (bs Binaries) WriteText(out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tGO\tMAIN\tARCH\tSTRIPPED\tPIE\tPCLNTAB")
+	for i := range bs {
+		fmt.Fprintln(w, bs[i].row())
+	}
+	w.Flush()
+}
+
+func (b *Binary) row() string {
+	if b.Error != "" {
+		return b.Path + "\terror: " + b.Error
+	}
+	unknown := func(s string) string {
+		if s == "" {
+			return "?"
+		}
+		return s
+	}
+	yes := func(v bool) string {
+		if v {
+			return "yes"
+		}
+		return "no"
+	}
+	return strings.Join([]string{b.Path, unknown(b.GoVersion), unknown(b.Main), unknown(b.Arch),
+		yes(b.Stripped), yes(b.PIE), b.Layout}, "\t")
+}
+
+// WriteText prints g the way the runtime prints goroutines in tracebacks.
+func (g *Goroutine) WriteText(out io.Writer) {
+	state := g.Status
+	if g.WaitReason != "" {
+		state = g.WaitReason
+	}
+	if g.Thread != 0 {
+		state += fmt.Sprintf(", thread %d", g.Thread)
+	}
+	fmt.Fprintf(out, "goroutine %d [%s]:\n", g.ID, state)
+	for _, f := range g.Stack {
+		for _, fr := range f.Frames {
+			if fr.Inlined {
+				fmt.Fprintf(out, "%s(...)\n\t%s:%d\n", fr.Func, fr.File, fr.Line)
+				continue
+			}
+			fmt.Fprintf(out, "%s()\n\t%s:%d +%#x fp=%#x sp=%#x pc=%#x\n", fr.Func, fr.File, fr.Line, f.Offset, f.FP, f.SP, f.PC)
+		}
+	}
+	if g.Error != "" {
+		fmt.Fprintf(out, "error: %s\n", g.Error)
+	}
+	if c := g.CreatedBy; c != nil {
+		fr := c.Frames[len(c.Frames)-1]
+		if len(c.Frames) > 1 {
+			fr.Func = c.Frames[0].Func
+		}
+		if g.Parent != 0 {
+			fmt.Fprintf(out, "created by %s in goroutine %d\n", fr.Func, g.Parent)
+		} else {
+			fmt.Fprintf(out, "created by %s\n", fr.Func)
+		}
+		fmt.Fprintf(out, "\t%s:%d +%#x\n", c.Frames[0].File, c.Frames[0].Line, c.Offset)
+	}
+}
+
+// WriteText prints gs separated by blank lines.
+func (gs Goroutines) WriteText(out io.Writer) {
+	for i := range gs {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		gs[i].WriteText(out)
+	}
+}
+
+// WriteText prints l as a table, one word of the frame per row.
+func (l *FrameLayout) WriteText(out io.Writer) {
+	writeFuncHeader(out, l.Func, l.File)
+	fmt.Fprintf(out, "pc %#x (+%#x), frame size %#x, stack map %d\n", l.PC, l.Offset, l.Size, l.StackMap)
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SP\tFP\tKIND\tPTR\tOBJECT\tARG")
+	signed := func(v int64) string {
+		if v < 0 {
+			return fmt.Sprintf("-%#x", -v)
+		}
+		return fmt.Sprintf("+%#x", v)
+	}
+	for _, s := range l.Slots {
+		ptr, obj, arg := "", "", ""
+		if s.Pointer {
+			ptr = "live"
+		}
+		if s.Object != nil {
+			obj = signed(int64(*s.Object))
+			if s.ObjectPointer {
+				obj += " ptr"
+			}
+		}
+		if a := s.Arg; a != nil {
+			arg = fmt.Sprintf("%d %s/%d", a.Index, signed(int64(a.Off)), a.Size)
+			if !a.Live {
+				arg += " dead"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", signed(s.SP), signed(s.FP), s.Kind, ptr, obj, arg)
+	}
+	w.Flush()
+	// the columns on the right are mostly empty
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		fmt.Fprintln(out, strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,440 @@
+package elf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var kindNames = [...]string{
+	kindBool:          "bool",
+	kindInt:           "int",
+	kindInt8:          "int8",
+	kindInt16:         "int16",
+	kindInt32:         "int32",
+	kindInt64:         "int64",
+	kindUint:          "uint",
+	kindUint8:         "uint8",
+	kindUint16:        "uint16",
+	kindUint32:        "uint32",
+	kindUint64:        "uint64",
+	kindUintptr:       "uintptr",
+	kindFloat32:       "float32",
+	kindFloat64:       "float64",
+	kindComplex64:     "complex64",
+	kindComplex128:    "complex128",
+	kindArray:         "array",
+	kindChan:          "chan",
+	kindFunc:          "func",
+	kindInterface:     "interface",
+	kindMap:           "map",
+	kindPtr:           "ptr",
+	kindSlice:         "slice",
+	kindString:        "string",
+	kindStruct:        "struct",
+	kindUnsafePointer: "unsafe.Pointer",
+}
+
+func (k kind) String() string {
+	if int(k) < len(kindNames) && kindNames[k] != "" {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", uint8(k))
+}
+
+var tflagNames = []string{"uncommon", "extraStar", "named", "regularMemory", "gcMaskOnDemand", "directIface"}
+
+func (t tflag) String() string {
+	var s []string
+	for i, n := range tflagNames {
+		if t&(1<<i) != 0 {
+			s = append(s, n)
+		}
+	}
+	if len(s) == 0 {
+		return "0"
+	}
+	return strings.Join(s, "|")
+}
+
+// the implementations of maps, told apart by their descriptors
+const (
+	mapBuckets  = iota // hash buckets, before go1.24
+	mapSwiss           // swiss tables, go1.24+
+	mapSwiss127        // swiss tables with split key and elem arrays
+)
+
+// the bits of name flags, see internal/abi.Name
+const (
+	nameExported = 1 << iota
+	nameHasTag
+	nameHasPkgPath
+	nameEmbedded // go1.19+
+)
+
+// decodeType decodes the type descriptor at t and its kind specific part.
+func (e *ELF_Info) decodeType(t uint64) *typeDesc {
+	w := e.dec.ptrSize
+	r := &structReader{decoder: &e.dec, b: e.read(t, 4*w+16)}
+	d := &typeDesc{}
+	d.addr = t
+	d.size = r.word("size")
+	d.ptrdata = r.word("ptrdata")
+	d.hash = r.uint32(r.next(4, 4))
+	d.tflag = tflag(r.uint8("tflag"))
+	d.align = r.uint8("align")
+	d.fieldAlign = r.uint8("fieldAlign")
+	d.kind = kind(r.uint8("kind") & kindMask)
+	r.word("equal")
+	d.gcdata = r.word("gcdata")
+	d.str = nameOff(r.uint32(r.next(4, 4)))
+	d.ptrToThis = typeOff(r.uint32(r.next(4, 4)))
+
+	r.b = e.mem(t)
+	var in, out uint16
+	var methods, fields slice
+	switch d.kind {
+	case kindArray:
+		d.array = &arraytype{r.word("elem"), r.word("slice"), r.word("len")}
+	case kindChan:
+		d.chan_ = &chantype{r.word("elem"), r.word("dir")}
+	case kindFunc:
+		in, out = r.uint16("inCount"), r.uint16("outCount")
+		d.fn = &functype{variadic: out&(1<<15) != 0}
+		out &= 1<<15 - 1
+	case kindInterface:
+		d.iface = &interfacetype{pkgpath: r.word("pkgpath")}
+		methods = r.slice("methods")
+	case kindMap:
+		d.map_ = e.decodeMap(r)
+	case kindPtr, kindSlice:
+		d.elem = r.word("elem")
+	case kindStruct:
+		d.struct_ = &structtype{pkgpath: r.word("pkgpath")}
+		fields = r.slice("fields")
+	}
+	r.next(0, w)
+	if d.tflag&tflagUncommon != 0 {
+		ut := t + uint64(r.off)
+		u := &uncommontype{}
+		u.pkgpath = nameOff(r.uint32(r.next(4, 4)))
+		u.mcount = r.uint16("mcount")
+		u.xcount = r.uint16("xcount")
+		u.moff = r.uint32(r.next(4, 4))
+		r.next(4, 4)
+		p := e.read(ut+uint64(u.moff), int(u.mcount)*16)
+		for i := 0; i < int(u.mcount); i++ {
+			u.methods = append(u.methods, method{
+				name: nameOff(e.dec.uint32(p[i*16:])),
+				mtyp: typeOff(e.dec.uint32(p[i*16+4:])),
+				ifn:  textOff(e.dec.uint32(p[i*16+8:])),
+				tfn:  textOff(e.dec.uint32(p[i*16+12:])),
+			})
+		}
+		d.uncommon = u
+	}
+	if r.short {
+		fail(fmt.Errorf("%w: type descriptor at %#x truncated", ErrMalformed, t))
+	}
+
+	switch {
+	case d.fn != nil:
+		// the parameters follow the uncommontype
+		p := e.read(t+uint64(r.off), (int(in)+int(out))*w)
+		for i := 0; i < int(in)+int(out); i++ {
+			pt := e.dec.uintptr(p[i*w:])
+			if i < int(in) {
+				d.fn.in = append(d.fn.in, pt)
+			} else {
+				d.fn.out = append(d.fn.out, pt)
+			}
+		}
+	case d.iface != nil:
+		p := e.read(methods.data, int(methods.len)*8)
+		for i := 0; i < int(methods.len); i++ {
+			d.iface.methods = append(d.iface.methods, imethod{
+				name: nameOff(e.dec.uint32(p[i*8:])),
+				ityp: typeOff(e.dec.uint32(p[i*8+4:])),
+			})
+		}
+	case d.struct_ != nil:
+		d.struct_.fields = e.structFields(d, fields)
+	}
+	return d
+}
+
+// decodeMap decodes the map specific part of a type descriptor.
+func (e *ELF_Info) decodeMap(r *structReader) *maptype {
+	m := &maptype{key: r.word("key"), elem: r.word("elem"), bucket: r.word("bucket")}
+	r.word("hasher")
+	n := len(r.fields)
+	switch e.mapLayout(m.bucket) {
+	case mapBuckets:
+		r.uint8("keysize")
+		r.uint8("elemsize")
+		r.uint16("bucketsize")
+	case mapSwiss:
+		r.word("groupSize")
+		r.word("slotSize")
+		r.word("elemOff")
+	case mapSwiss127:
+		r.word("groupSize")
+		r.word("keysOff")
+		r.word("keyStride")
+		r.word("elemsOff")
+		r.word("elemStride")
+		r.word("elemOff")
+	}
+	r.record("flags", uint64(r.uint32(r.next(4, 4))))
+	m.sizes = r.fields[n:]
+	return m
+}
+
+// mapLayout returns the implementation of maps from the layout version and
+// the name of the type of the buckets or groups.
+func (e *ELF_Info) mapLayout(bucket uint64) int {
+	if e.ver >= ver127 {
+		return mapSwiss127
+	}
+	if e.ver >= ver121 && strings.Contains(e.typeName(bucket), "map.group[") {
+		return mapSwiss
+	}
+	return mapBuckets
+}
+
+// structFields decodes the fields of the struct type d from the slice f.
+func (e *ELF_Info) structFields(d *typeDesc, f slice) []structfield {
+	w := e.dec.ptrSize
+	p := e.read(f.data, int(f.len)*3*w)
+	fields := make([]structfield, f.len)
+	named := false // the embedded flag is in the name
+	for i := range fields {
+		fields[i] = structfield{
+			name:   e.dec.uintptr(p[i*3*w:]),
+			typ:    e.dec.uintptr(p[i*3*w+w:]),
+			offset: e.dec.uintptr(p[i*3*w+2*w:]),
+		}
+		if b := e.mem(fields[i].name); len(b) > 0 && b[0]&nameEmbedded != 0 {
+			named = true
+		}
+	}
+	// before go1.19 the offset is shifted left with the embedded flag in the
+	// low bit. go1.18 and go1.19 share a layout: unless the build info tells
+	// them apart, if no name has the flag, the offsets are shifted when the
+	// last field would overrun the struct.
+	shifted := e.ver < ver118
+	if e.ver == ver118 && e.release != 0 {
+		shifted = e.release < 19
+	} else if e.ver == ver118 && !named && len(fields) > 0 {
+		last := fields[len(fields)-1]
+		shifted = last.offset+e.typeSize(last.typ) > d.size
+	}
+	for i := range fields {
+		if shifted {
+			fields[i].embedded = fields[i].offset&1 != 0
+			fields[i].offset >>= 1
+		} else if b := e.mem(fields[i].name); len(b) > 0 {
+			fields[i].embedded = b[0]&nameEmbedded != 0
+		}
+	}
+	return fields
+}
+
+// typeSize returns the size of the type at t.
+func (e *ELF_Info) typeSize(t uint64) uint64 {
+	return e.dec.uintptr(e.read(t, e.dec.ptrSize))
+}
+
+// typeRef returns a reference to the type at t.
+func (e *ELF_Info) typeRef(t uint64) TypeRef {
+	if t == 0 {
+		return TypeRef{}
+	}
+	return TypeRef{t, e.typeName(t)}
+}
+
+// typeRefPtr is typeRef for optional references, nil if t is 0.
+func (e *ELF_Info) typeRefPtr(t uint64) *TypeRef {
+	if t == 0 {
+		return nil
+	}
+	r := e.typeRef(t)
+	return &r
+}
+
+// PrintTypeDescs prints the types like PrintTypes, each followed by the
+// contents of its descriptor.
+func (e *ELF_Info) PrintTypeDescs(out io.Writer) error {
+	types, err := e.Types(true)
+	return writeText(out, types, err)
+}
+
+// typeDescOf returns the contents of the type descriptor d, resolving the
+// names and types it refers to.
+func (e *ELF_Info) typeDescOf(d *typeDesc) *TypeDesc {
+	td := &TypeDesc{
+		Kind:       d.kind.String(),
+		Size:       d.size,
+		Align:      d.align,
+		FieldAlign: d.fieldAlign,
+		PtrData:    d.ptrdata,
+		TFlag:      d.tflag.String(),
+		PkgPath:    e.pkgPath(d),
+	}
+	if d.ptrToThis != 0 {
+		td.PtrToThis = e.typeRefPtr(e.module.types + uint64(d.ptrToThis))
+	}
+	switch {
+	case d.elem != 0:
+		td.Elem = e.typeRefPtr(d.elem)
+	case d.array != nil:
+		td.Elem = e.typeRefPtr(d.array.elem)
+		td.Slice = e.typeRefPtr(d.array.slice)
+		td.Len = d.array.len
+	case d.chan_ != nil:
+		td.Elem = e.typeRefPtr(d.chan_.elem)
+		td.Dir = chanDir(d.chan_.dir)
+	case d.fn != nil:
+		for _, t := range d.fn.in {
+			td.In = append(td.In, e.typeRef(t))
+		}
+		for _, t := range d.fn.out {
+			td.Out = append(td.Out, e.typeRef(t))
+		}
+		td.Variadic = d.fn.variadic
+	case d.iface != nil:
+		for _, m := range d.iface.methods {
+			td.Methods = append(td.Methods, IMethod{
+				Name: e.name(e.module.types + uint64(m.name)),
+				Type: e.typeRef(e.module.types + uint64(m.ityp)),
+			})
+		}
+	case d.map_ != nil:
+		td.Key = e.typeRefPtr(d.map_.key)
+		td.Elem = e.typeRefPtr(d.map_.elem)
+		td.Bucket = e.typeRefPtr(d.map_.bucket)
+		for _, f := range d.map_.sizes {
+			td.MapSizes = append(td.MapSizes, Field{f.name, f.value})
+		}
+	case d.struct_ != nil:
+		td.Fields = e.structFieldsOf(d.struct_)
+	}
+	return td
+}
+
+// structFieldsOf returns the fields of the struct type s with their names,
+// types and tags.
+func (e *ELF_Info) structFieldsOf(s *structtype) []StructField {
+	fields := []StructField{}
+	for _, f := range s.fields {
+		name, tag, _ := e.decodeName(f.name)
+		fields = append(fields, StructField{
+			Name:     name,
+			Type:     e.typeRef(f.typ),
+			Offset:   f.offset,
+			Embedded: f.embedded,
+			Tag:      tag,
+		})
+	}
+	return fields
+}
+
+// pkgPath returns the package path of the type d, from its uncommontype or,
+// for structs and interfaces, the kind specific part.
+func (e *ELF_Info) pkgPath(d *typeDesc) string {
+	if d.uncommon != nil && d.uncommon.pkgpath != 0 {
+		return e.name(e.module.types + uint64(d.uncommon.pkgpath))
+	}
+	switch {
+	case d.struct_ != nil && d.struct_.pkgpath != 0:
+		return e.name(d.struct_.pkgpath)
+	case d.iface != nil && d.iface.pkgpath != 0:
+		return e.name(d.iface.pkgpath)
+	}
+	return ""
+}
+
+func chanDir(d uint64) string {
+	switch d {
+	case 1:
+		return "recv"
+	case 2:
+		return "send"
+	case 3:
+		return "both"
+	}
+	return fmt.Sprint(d)
+}
+
+// refs returns the addresses of the types the type d refers to.
+func (e *ELF_Info) refs(d *typeDesc) []uint64 {
+	var refs []uint64
+	if d.ptrToThis != 0 {
+		refs = append(refs, e.module.types+uint64(d.ptrToThis))
+	}
+	switch {
+	case d.elem != 0:
+		refs = append(refs, d.elem)
+	case d.array != nil:
+		refs = append(refs, d.array.elem, d.array.slice)
+	case d.chan_ != nil:
+		refs = append(refs, d.chan_.elem)
+	case d.fn != nil:
+		refs = append(append(refs, d.fn.in...), d.fn.out...)
+	case d.iface != nil:
+		for _, m := range d.iface.methods {
+			refs = append(refs, e.module.types+uint64(m.ityp))
+		}
+	case d.map_ != nil:
+		refs = append(refs, d.map_.key, d.map_.elem)
+	case d.struct_ != nil:
+		for _, f := range d.struct_.fields {
+			refs = append(refs, f.typ)
+		}
+	}
+	if d.uncommon != nil {
+		for _, m := range d.uncommon.methods {
+			if m.mtyp != -1 {
+				refs = append(refs, e.module.types+uint64(m.mtyp))
+			}
+		}
+	}
+	return refs
+}
+
+// allTypes returns the types listed by loadTypeLinks, those of the itabs and
+// those reachable from them, sorted by address. Named types are mostly found
+// this way, the linker lists only the types reflect may have to look up by
+// structure.
+func (e *ELF_Info) allTypes() ([]uint64, error) {
+	if err := e.loadTypeLinks(); err != nil {
+		return nil, err
+	}
+	itabs, err := e.itabs()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[uint64]bool)
+	var all []uint64
+	queue := append([]uint64(nil), e.types...)
+	w := uint64(e.dec.ptrSize)
+	for _, p := range itabs {
+		b := e.read(p, int(2*w))
+		queue = append(queue, e.dec.uintptr(b), e.dec.uintptr(b[w:]))
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if t == 0 || seen[t] || !e.mapped(t) {
+			continue
+		}
+		seen[t] = true
+		all = append(all, t)
+		queue = append(queue, e.refs(e.decodeType(t))...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i] < all[j]
+	})
+	return all, nil
+}
@@ -1,79 +1,87 @@
 package elf
 
-import "unsafe"
-
 // copied from go/src/runtime/symtab.go, copyright belongs to the Go authors.
 
-// pcHeader holds data used by the pclntab lookups.
+// pcHeader holds data used by the pclntab lookups, decoded from the start of
+// the pclntab.
 type pcHeader struct {
-	magic          uint32  // 0xFFFFFFF0
-	pad1, pad2     uint8   // 0,0
-	minLC          uint8   // min instruction size
-	ptrSize        uint8   // size of a ptr in bytes
-	nfunc          int     // number of functions in the module
-	nfiles         uint    // number of entries in the file tab
-	textStart      uintptr // base for function entry PC offsets in this module, equal to moduledata.text
-	funcnameOffset uintptr // offset to the funcnametab variable from pcHeader
-	cuOffset       uintptr // offset to the cutab variable from pcHeader
-	filetabOffset  uintptr // offset to the filetab variable from pcHeader
-	pctabOffset    uintptr // offset to the pctab variable from pcHeader
-	pclnOffset     uintptr // offset to the pclntab variable from pcHeader
+	magic          uint32 // see layouts
+	minLC          uint8  // min instruction size
+	ptrSize        uint8  // size of a ptr in bytes
+	nfunc          int    // number of functions in the module
+	nfiles         uint   // number of entries in the file tab
+	textStart      uint64 // base for function entry PC offsets, go1.18 and go1.19 only
+	funcnameOffset uint64 // offset to the funcnametab variable from pcHeader
+	cuOffset       uint64 // offset to the cutab variable from pcHeader
+	filetabOffset  uint64 // offset to the filetab variable from pcHeader
+	pctabOffset    uint64 // offset to the pctab variable from pcHeader
+	pclnOffset     uint64 // offset to the pclntab variable from pcHeader
 }
 
 // moduledata records information about the layout of the executable
 // image. It is written by the linker. Any changes here must be
 // matched changes to the code in cmd/link/internal/ld/symtab.go:symtab.
-// moduledata is stored in statically allocated non-pointer memory;
-// none of the pointers here are visible to the garbage collector.
+//
+// This is the decoded form of the union of all the supported layouts,
+// pointers are addresses in the target. Fields missing from the layout of
+// the binary are left zero, fields holds the ones actually present.
 type moduledata struct {
-	pcHeader     *pcHeader
-	funcnametab  []byte
-	cutab        []uint32
-	filetab      []byte
-	pctab        []byte
-	pclntable    []byte
-	ftab         []functab
-	findfunctab  uintptr
-	minpc, maxpc uintptr
-
-	text, etext           uintptr
-	noptrdata, enoptrdata uintptr
-	data, edata           uintptr
-	bss, ebss             uintptr
-	noptrbss, enoptrbss   uintptr
-	end, gcdata, gcbss    uintptr
-	types, etypes         uintptr
-	rodata                uintptr
-	gofunc                uintptr // go.func.*
-
-	textsectmap []textsect
-	typelinks   []int32 // offsets from types
-	itablinks   []*itab
-
-	ptab []ptabEntry
-
-	pluginpath string
-	pkghashes  []modulehash
-
-	modulename   string
-	modulehashes []modulehash
+	pcHeader     uint64
+	funcnametab  slice
+	cutab        slice
+	filetab      slice
+	pctab        slice
+	pclntable    slice
+	ftab         slice
+	findfunctab  uint64
+	minpc, maxpc uint64
+
+	text, etext           uint64
+	noptrdata, enoptrdata uint64
+	data, edata           uint64
+	bss, ebss             uint64
+	noptrbss, enoptrbss   uint64
+	covctrs, ecovctrs     uint64 // go1.20
+	end, gcdata, gcbss    uint64
+	types, etypes         uint64
+	typedesclen           uint64 // go1.27
+	itaboffset, itabsize  uint64 // go1.27
+	rodata                uint64 // go1.18
+	gofunc                uint64 // go.func.*, go1.18
+	epclntab              uint64 // go1.27
+
+	textsectmap slice
+	typelinks   slice // offsets from types, before go1.27
+	itablinks   slice // before go1.27
+
+	ptab slice
+
+	pluginpath slice
+	pkghashes  slice
+
+	inittasks slice // go1.21
+
+	modulename   slice
+	modulehashes slice
 
 	hasmain uint8 // 1 if module contains the main function, 0 otherwise
 
 	gcdatamask, gcbssmask bitvector
 
-	typemap map[typeOff]*_type // offset to *_rtype in previous module
+	typemap uint64
 
 	bad bool // module failed to load and should be ignored
 
-	next *moduledata
+	next uint64
+
+	fields []field // fields in the order of the layout
 }
 
 // Information from the compiler about the layout of stack frames.
 // Note: this type must agree with reflect.bitVector.
 type bitvector struct {
 	n        int32 // # of bits
-	bytedata *uint8
+	bytedata uint64
 }
 
 // A ptabEntry is generated by the compiler for each exported function
@@ -102,11 +110,6 @@ type modulehash struct {
 	runtimehash  *string
 }
 
-type functab struct {
-	entryoff uint32 // relative to runtime.text
-	funcoff  uint32
-}
-
 // Mapping information for secondary text sections
 
 type textsect struct {
@@ -115,16 +118,13 @@ type textsect struct {
 	baseaddr uintptr // relocated section address
 }
 
-// layout of Itab known to compilers
-// allocated in non-garbage-collected memory
-// Needs to be in sync with
-// ../cmd/compile/internal/reflectdata/reflect.go:/^func.WriteTabs.
+// itab is the decoded form of an interface table, see internal/abi.ITab.
 type itab struct {
-	inter *interfacetype
-	_type *_type
-	hash  uint32 // copy of _type.hash. Used for type switches.
-	_     [4]byte
-	fun   [1]uintptr // variable sized. fun[0]==0 means _type does not implement inter.
+	addr  uint64
+	inter uint64   // the interface type
+	typ   uint64   // the concrete type
+	hash  uint32   // copy of typ's hash
+	fun   []uint64 // fun[0]==0 means typ does not implement inter
 }
 
 type nameOff int32
@@ -143,7 +143,7 @@ type uncommontype struct {
 	mcount  uint16 // number of methods
 	xcount  uint16 // number of exported methods
 	moff    uint32 // offset from this uncommontype to [mcount]method
-	_       uint32 // unused
+	methods []method
 }
 
 type imethod struct {
@@ -151,72 +151,50 @@ type imethod struct {
 	ityp typeOff
 }
 
+// The kind specific parts of the type descriptors, decoded. Pointers to
+// other descriptors and names are kept as addresses.
+
 type interfacetype struct {
-	typ     _type
-	pkgpath name
-	mhdr    []imethod
+	pkgpath uint64
+	methods []imethod
 }
 
 type maptype struct {
-	typ    _type
-	key    *_type
-	elem   *_type
-	bucket *_type // internal type representing a hash bucket
-	// function for hashing keys (ptr to key, seed) -> hash
-	hasher     func(unsafe.Pointer, uintptr) uintptr
-	keysize    uint8  // size of key slot
-	elemsize   uint8  // size of elem slot
-	bucketsize uint16 // size of bucket
-	flags      uint32
+	key    uint64
+	elem   uint64
+	bucket uint64  // internal type representing a hash bucket, or a group of swiss maps
+	sizes  []field // the sizes and flags, which vary with the map implementation
 }
 
 type arraytype struct {
-	typ   _type
-	elem  *_type
-	slice *_type
-	len   uintptr
+	elem  uint64
+	slice uint64
+	len   uint64
 }
 
 type chantype struct {
-	typ  _type
-	elem *_type
-	dir  uintptr
-}
-
-type slicetype struct {
-	typ  _type
-	elem *_type
+	elem uint64
+	dir  uint64
 }
 
 type functype struct {
-	typ      _type
-	inCount  uint16
-	outCount uint16
-}
-
-type ptrtype struct {
-	typ  _type
-	elem *_type
+	in       []uint64
+	out      []uint64
+	variadic bool
 }
 
 type structfield struct {
-	name   name
-	typ    *_type
-	offset uintptr
+	name     uint64
+	typ      uint64
+	offset   uint64
+	embedded bool
 }
 
 type structtype struct {
-	typ     _type
-	pkgPath name
+	pkgpath uint64
 	fields  []structfield
 }
 
-// name is an encoded type name with optional extra data.
-// See reflect/type.go for details.
-type name struct {
-	bytes *byte
-}
-
 // tflag is documented in reflect/type.go.
 //
 // tflag values must be kept in sync with copies in:
@@ -228,42 +206,89 @@ type name struct {
 type tflag uint8
 
 const (
-	tflagUncommon      tflag = 1 << 0
-	tflagExtraStar     tflag = 1 << 1
-	tflagNamed         tflag = 1 << 2
-	tflagRegularMemory tflag = 1 << 3 // equal and hash can treat values of this type as a single region of t.size bytes
+	tflagUncommon       tflag = 1 << 0
+	tflagExtraStar      tflag = 1 << 1
+	tflagNamed          tflag = 1 << 2
+	tflagRegularMemory  tflag = 1 << 3 // equal and hash can treat values of this type as a single region of t.size bytes
+	tflagGCMaskOnDemand tflag = 1 << 4 // go1.24+, gcdata points to the pointer to the mask built at run time
+	tflagDirectIface    tflag = 1 << 5 // a bit of kind in older releases
+)
+
+// kind is the kind of a type as stored in the low bits of _type.kind,
+// see reflect.Kind.
+type kind uint8
+
+const (
+	kindBool kind = 1 + iota
+	kindInt
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+	kindUint
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+	kindUintptr
+	kindFloat32
+	kindFloat64
+	kindComplex64
+	kindComplex128
+	kindArray
+	kindChan
+	kindFunc
+	kindInterface
+	kindMap
+	kindPtr
+	kindSlice
+	kindString
+	kindStruct
+	kindUnsafePointer
+
+	kindMask = (1 << 5) - 1
 )
 
-// Needs to be in sync with ../cmd/link/internal/ld/decodesym.go:/^func.commonsize,
-// ../cmd/compile/internal/reflectdata/reflect.go:/^func.dcommontype and
-// ../reflect/type.go:/^type.rtype.
-// ../internal/reflectlite/type.go:/^type.rtype.
+// _type is the decoded form of the header common to all type descriptors,
+// see internal/abi.Type.
 type _type struct {
-	size       uintptr
-	ptrdata    uintptr // size of memory prefix holding all pointers
+	addr       uint64 // address of the descriptor
+	size       uint64
+	ptrdata    uint64 // size of memory prefix holding all pointers
 	hash       uint32
 	tflag      tflag
 	align      uint8
 	fieldAlign uint8
-	kind       uint8
-	// function for comparing objects of this type
-	// (ptr to object A, ptr to object B) -> ==?
-	equal func(unsafe.Pointer, unsafe.Pointer) bool
-	// gcdata stores the GC type data for the garbage collector.
-	// If the KindGCProg bit is set in kind, gcdata is a GC program.
-	// Otherwise it is a ptrmask bitmap. See mbitmap.go for details.
-	gcdata    *byte
-	str       nameOff
-	ptrToThis typeOff
+	kind       kind
+	gcdata     uint64
+	str        nameOff
+	ptrToThis  typeOff
+}
+
+// typeDesc is a type descriptor decoded with its kind specific part, only
+// the one matching the kind is set.
+type typeDesc struct {
+	_type
+	uncommon *uncommontype
+	elem     uint64 // pointer and slice
+	array    *arraytype
+	chan_    *chantype
+	fn       *functype
+	iface    *interfacetype
+	map_     *maptype
+	struct_  *structtype
 }
 
 // Layout of in-memory per-function information prepared by linker
 // See https://golang.org/s/go12symtab.
 // Keep in sync with linker (../cmd/link/internal/ld/pcln.go:/pclntab)
 // and with package debug/gosym and with symtab.go in package runtime.
+//
+// This is the decoded form, the record in the binary is followed by npcdata
+// uint32 pcdata offsets and nfuncdata funcdata references.
 type _func struct {
-	entryoff uint32 // start pc, as offset from moduledata.text/pcHeader.textStart
-	nameoff  int32  // function name
+	entry   uint64 // start pc
+	nameoff int32  // function name
 
 	args        int32  // in/out args size
 	deferreturn uint32 // offset of start of a deferreturn call instruction from entry, if any.
@@ -273,28 +298,42 @@ type _func struct {
 	pcln      uint32
 	npcdata   uint32
 	cuOffset  uint32 // runtime.cutab offset of this function's CU
+	startLine int32  // line number of start of function, go1.20 and later
 	funcID    funcID // set for certain special runtime functions
 	flag      funcFlag
-	_         [1]byte // pad
-	nfuncdata uint8   // must be last, must end on a uint32-aligned boundary
+	nfuncdata uint8
+
+	pcdata int // offset of the pcdata array in pclntable
 }
 
 type stackmap struct {
-	n        int32   // number of bitmaps
-	nbit     int32   // number of bits in each bitmap
-	bytedata [1]byte // bitmaps, each starting on a byte boundary
+	n        int32  // number of bitmaps
+	nbit     int32  // number of bits in each bitmap
+	bytedata []byte // bitmaps, each starting on a byte boundary
 }
 
 // A stackObjectRecord is generated by the compiler for each stack object in a stack frame.
 // This record must match the generator code in cmd/compile/internal/liveness/plive.go:emitStackObjects.
+//
+// This is the decoded form, before go1.18 the record only held the offset
+// and a *_type, which size, ptrdata and gcdata are taken from.
 type stackObjectRecord struct {
 	// offset in frame
 	// if negative, offset from varp
 	// if non-negative, offset from argp
-	off       int32
-	size      int32
-	_ptrdata  int32  // ptrdata, or -ptrdata is GC prog is used
-	gcdataoff uint32 // offset to gcdata from moduledata.rodata
+	off      int32
+	size     int32
+	_ptrdata int32  // ptrdata, or -ptrdata is GC prog is used
+	gcdata   uint64 // address of the gcdata
+}
+
+// inlinedCall is an entry of the inlining tree of a function
+// (_FUNCDATA_InlTree), decoded from either layout.
+type inlinedCall struct {
+	funcID    funcID
+	nameOff   int32 // offset into funcnametab of the inlined function
+	parentPc  int32 // offset from the entry of the caller of the inlined call
+	startLine int32 // go1.20+ only
 }
 
 type funcID uint8
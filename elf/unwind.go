@@ -0,0 +1,202 @@
+package elf
+
+import (
+	"fmt"
+	"io"
+)
+
+// _func.flag bits, go1.17+
+const (
+	funcFlagTopFrame funcFlag = 1 << 0 // the function is the top of the stack
+	funcFlagSPWrite  funcFlag = 1 << 1 // the function writes sp in ways pcsp does not track
+)
+
+// maxFrames is the number of frames unwind walks before giving up.
+const maxFrames = 1024
+
+// topFrames are the functions at the top of the stack in binaries without
+// funcFlagTopFrame, and those on the system stack. They are known by name
+// as the funcIDs marking them are renumbered across releases.
+var topFrames = map[string]bool{
+	"runtime.goexit":    true,
+	"runtime.mstart":    true,
+	"runtime.rt0_go":    true,
+	"runtime.mcall":     true,
+	"runtime.morestack": true,
+}
+
+// injectedCalls are the functions the runtime makes look like they were
+// called at the pc where a signal interrupted the goroutine, also known by
+// name rather than funcID.
+var injectedCalls = map[string]bool{
+	"runtime.sigpanic":     true,
+	"runtime.asyncPreempt": true,
+	"runtime.debugCallV2":  true,
+}
+
+// memory reads the memory of a process running the binary, from the binary
+// where the process memory lacks it and the binary holds what the process
+// has there, e.g. the text missing from core files. The writable data of the
+// binary only holds its initial values, so it is never read.
+type memory struct {
+	e    *ELF_Info
+	mem  io.ReaderAt
+	bias uint64 // load address minus link address of the binary
+}
+
+// read returns the n bytes at addr, failing the decoding if they are not
+// in memory.
+func (m *memory) read(addr uint64, n int) []byte {
+	b := make([]byte, n)
+	if _, err := m.mem.ReadAt(b, int64(addr)); err == nil {
+		return b
+	}
+	if addr >= m.bias {
+		a := addr - m.bias
+		for _, s := range m.e.secs {
+			if !s.writable && a >= s.addr && a+uint64(n) <= s.addr+s.filesz {
+				return s.contents()[a-s.addr:][:n]
+			}
+		}
+	}
+	fail(fmt.Errorf("%d bytes at %#x not in memory", n, addr))
+	return nil
+}
+
+func (m *memory) uintptr(addr uint64) uint64 {
+	return m.e.dec.uintptr(m.read(addr, m.e.dec.ptrSize))
+}
+
+func (m *memory) uint32(addr uint64) uint32 {
+	return m.e.dec.uint32(m.read(addr, 4))
+}
+
+func (m *memory) uint64(addr uint64) uint64 {
+	return m.e.dec.order.Uint64(m.read(addr, 8))
+}
+
+// string reads the string which header is at addr.
+func (m *memory) string(addr uint64) string {
+	p, n := m.uintptr(addr), m.uintptr(addr+uint64(m.e.dec.ptrSize))
+	if n == 0 {
+		return ""
+	}
+	if n > 1<<20 {
+		fail(fmt.Errorf("%w: string of %d bytes at %#x", ErrMalformed, n, p))
+	}
+	return string(m.read(p, int(n)))
+}
+
+// usesLR reports whether calls leave the return address in a link register
+// rather than pushing it, i.e. on all architectures but 386 and amd64.
+func (e *ELF_Info) usesLR() bool {
+	a := e.obj.arch()
+	return a != "386" && a != "amd64"
+}
+
+// minFrameSize returns the size of the area at the bottom of each frame
+// reserved for the callee on link register architectures, and the
+// alignment of the stack pointer.
+func (e *ELF_Info) minFrameSize() (size, align uint64) {
+	align = uint64(e.dec.ptrSize)
+	switch e.obj.arch() {
+	case "386", "amd64":
+		return 0, align
+	case "arm64":
+		return 8, 16
+	case "ppc64", "ppc64le":
+		return 32, 16
+	}
+	return align, align
+}
+
+// Unwind walks the stack of a goroutine of a process running the binary,
+// from its innermost frame at pc and sp, lr being the link register there
+// on architectures which have one. It does it the way the runtime does in
+// tracebacks: the pcsp table of each function gives the size of its frame
+// and so where the return address is, and the flags of the function tell
+// where the stack starts (TOPFRAME) or cannot be walked further (SPWRITE).
+// Calls inlined at each pc are expanded into the frames of the physical one.
+//
+// p.Mem reads the memory of the process, at virtual addresses, the binary
+// being loaded p.Bias bytes above its link address, as PIE binaries are.
+// The memory the binary holds, like its text, is read from it when p.Mem
+// fails. p.Threads is not used. Unwind returns the frames up to the top of
+// the stack, or up to a frame it cannot walk past with err telling why.
+func (e *ELF_Info) Unwind(p *Process, pc, sp, lr uint64) (frames []StackFrame, err error) {
+	defer catch(&err)
+	if err := e.loadpcln(); err != nil {
+		return nil, err
+	}
+	return e.unwind(&memory{e, p.Mem, p.Bias}, pc, sp, lr)
+}
+
+// unwind is Unwind with m reading the memory of the process.
+func (e *ELF_Info) unwind(m *memory, pc, sp, lr uint64) (frames []StackFrame, err error) {
+	defer catch(&err)
+	usesLR := e.usesLR()
+	w := uint64(e.dec.ptrSize)
+	innermost, trap := true, false
+	for len(frames) < maxFrames {
+		f := e.findFuncPC(pc - m.bias)
+		if f == nil {
+			return frames, fmt.Errorf("unknown pc %#x", pc)
+		}
+		name := e.getFuncName(f)
+		spdelta, _ := e.pcvalueAt(f, f.pcsp, pc-m.bias)
+		fp := sp + uint64(spdelta)
+		if !usesLR {
+			// the call pushed the return address
+			fp += w
+		}
+		// the pc of a caller is the return address, after the call
+		tracepc := pc - m.bias
+		if !innermost && !trap && tracepc > f.entry {
+			tracepc--
+		}
+		frames = append(frames, StackFrame{
+			PC:     pc,
+			SP:     sp,
+			FP:     fp,
+			Func:   name,
+			Offset: pc - m.bias - f.entry,
+			Frames: e.frames(f, tracepc),
+		})
+		if f.flag&funcFlagTopFrame != 0 || topFrames[name] {
+			return frames, nil
+		}
+		if f.flag&funcFlagSPWrite != 0 && !innermost {
+			// sp may not be what pcsp says, but for the innermost frame
+			// stopped at a pc where it is
+			return frames, fmt.Errorf("%s writes sp, cannot unwind past it", name)
+		}
+		switch {
+		case !usesLR:
+			lr = m.uintptr(fp - w)
+		case innermost && sp < fp || lr == 0:
+			// the function saved the link register at the bottom of its frame
+			lr = m.uintptr(sp)
+		}
+		if lr == 0 {
+			return frames, nil
+		}
+		if pc == lr && sp == fp {
+			return frames, fmt.Errorf("stuck at %s", name)
+		}
+		pc, sp, lr = lr, fp, 0
+		trap = injectedCalls[name]
+		if usesLR && trap {
+			// the signal handler saved the link register before faking the call
+			x := m.uintptr(sp)
+			size, align := e.minFrameSize()
+			sp += (size + align - 1) &^ (align - 1)
+			if f := e.findFuncPC(pc - m.bias); f == nil {
+				pc = x
+			} else if spdelta, _ := e.pcvalueAt(f, f.pcsp, pc-m.bias); spdelta == 0 {
+				lr = x
+			}
+		}
+		innermost = false
+	}
+	return frames, fmt.Errorf("more than %d frames", maxFrames)
+}
@@ -0,0 +1,79 @@
+package elf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// magic numbers at the start of the pcHeader, see go/src/internal/abi/symtab.go.
+const (
+	go12magic  = 0xfffffffb
+	go116magic = 0xfffffffa
+	go118magic = 0xfffffff0
+	go120magic = 0xfffffff1
+)
+
+// goVersion identifies a layout of the runtime data structures by the oldest
+// Go release that uses it.
+type goVersion int
+
+const (
+	ver116 goVersion = 116
+	ver117 goVersion = 117 // names are varint prefixed, _func.flag is set
+	ver118 goVersion = 118 // _func.entryoff, funcdata as offsets from moduledata.gofunc
+	ver120 goVersion = 120 // _func.startLine, moduledata.covctrs
+	ver121 goVersion = 121 // moduledata.inittasks
+	ver127 goVersion = 127 // typelinks/itablinks replaced by typedesclen/itaboffset
+)
+
+func (v goVersion) String() string {
+	return fmt.Sprintf("go%d.%d", v/100, v%100)
+}
+
+// layouts maps a pclntab magic to the moduledata layouts that may follow it,
+// newest first. Releases sharing a magic are told apart by decoding the
+// moduledata with each layout in turn and keeping the first consistent one.
+var layouts = map[uint32][]goVersion{
+	go116magic: {ver116},
+	go118magic: {ver118},
+	go120magic: {ver127, ver121, ver120},
+}
+
+// layoutFor returns the candidate layouts for magic.
+func layoutFor(magic uint32) ([]goVersion, error) {
+	if v, ok := layouts[magic]; ok {
+		return v, nil
+	}
+	if magic == go12magic {
+		return nil, &UnsupportedVersionError{magic, "binaries built with Go 1.2-1.15 are not supported"}
+	}
+	return nil, &UnsupportedVersionError{magic, "unknown pclntab magic"}
+}
+
+// parseRelease returns the minor release of a Go version string like
+// go1.21.3, go1.22rc1 or "devel go1.27-abcdef", 0 if it is not one.
+func parseRelease(v string) int {
+	if i := strings.Index(v, "go1."); i >= 0 {
+		v = v[i+len("go1."):]
+		n := 0
+		for n < len(v) && v[n] >= '0' && v[n] <= '9' {
+			n++
+		}
+		if r, err := strconv.Atoi(v[:n]); err == nil {
+			return r
+		}
+	}
+	return 0
+}
+
+// forRelease moves the layout of release, the newest one not after it, to
+// the front of vers.
+func forRelease(vers []goVersion, release int) []goVersion {
+	for i, v := range vers {
+		if int(v) <= 100+release {
+			return append(append([]goVersion{v}, vers[:i]...), vers[i+1:]...)
+		}
+	}
+	return vers
+}
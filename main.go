@@ -1,53 +1,296 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
 
 	"github.com/voidpx/gobjdump/elf"
 	"github.com/spf13/cobra"
 )
 
+// exit codes, any other error exits with 1
+const (
+	exitNotGo        = 2 // not a Go binary or built with an unsupported Go version
+	exitFuncNotFound = 3 // the function, type or pc asked for is not in the binary
+)
+
+func exitCode(err error) int {
+	var ve *elf.UnsupportedVersionError
+	var fe *elf.FuncNotFoundError
+	var te *elf.TypeNotFoundError
+	var pe *elf.PCNotFoundError
+	switch {
+	case errors.Is(err, elf.ErrNotGoBinary), errors.As(err, &ve):
+		return exitNotGo
+	case errors.As(err, &fe), errors.As(err, &te), errors.As(err, &pe):
+		return exitFuncNotFound
+	}
+	return 1
+}
+
+// parseAddrs parses addresses given in hex (0x prefixed), octal or decimal.
+func parseAddrs(args []string) ([]uint64, error) {
+	addrs := make([]uint64, len(args))
+	for i, a := range args {
+		v, err := strconv.ParseUint(a, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q", a)
+		}
+		addrs[i] = v
+	}
+	return addrs, nil
+}
+
+// output formats
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatJSONL = "jsonl" // one line per element of listings
+)
+
+// textWriter is implemented by the data returned by the commands.
+type textWriter interface {
+	WriteText(out io.Writer)
+}
+
+// envelope wraps the data written as JSON with the version of its schema.
+type envelope struct {
+	Schema int `json:"schema"`
+	Data   any `json:"data"`
+}
+
+// write writes v in format. In jsonl, listings are written one element per
+// line, each in its envelope.
+func write(out io.Writer, format string, v textWriter) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(envelope{elf.SchemaVersion, v})
+	case formatJSONL:
+		enc := json.NewEncoder(out)
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return enc.Encode(envelope{elf.SchemaVersion, v})
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := enc.Encode(envelope{elf.SchemaVersion, rv.Index(i).Interface()}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	v.WriteText(out)
+	return nil
+}
+
+// list is a list of dumps, written one after another.
+type list []textWriter
+
+func (l list) WriteText(out io.Writer) {
+	for _, v := range l {
+		v.WriteText(out)
+	}
+}
+
+// writeEach writes the n dumps returned by get in order, as a list. They are
+// computed by up to GOMAXPROCS goroutines and written as soon as those before
+// them are, but for json which is a single document. It returns once no get
+// is running, so the binary can be closed then, even on error.
+func writeEach(out io.Writer, format string, n int, get func(i int) (textWriter, error)) error {
+	type result struct {
+		v    textWriter
+		err  error
+		done chan struct{}
+	}
+	results := make([]result, n)
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+	next := make(chan int)
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	// stop feeding the workers, then wait for them
+	defer wg.Wait()
+	defer close(quit)
+	go func() {
+		defer close(next)
+		for i := 0; i < n; i++ {
+			select {
+			case next <- i:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i].v, results[i].err = get(i)
+				close(results[i].done)
+			}
+		}()
+	}
+	l := list{}
+	for i := range results {
+		r := &results[i]
+		<-r.done
+		if r.err != nil {
+			return r.err
+		}
+		if format == formatJSON {
+			l = append(l, r.v)
+		} else if err := write(out, format, list{r.v}); err != nil {
+			return err
+		}
+		r.v = nil
+	}
+	if format == formatJSON {
+		return write(out, format, l)
+	}
+	return nil
+}
+
 func main() {
+	var format string
 	cmd := &cobra.Command{
 		Use:   "gobjdump <command> <file>",
-		Short: "ELF dumper for binaries built with Go",
-		Long: `gobjdump prints information specific to Go in an ELF executable built with Go. e.g.
+		Short: "ELF/PE/Mach-O dumper for binaries built with Go",
+		Long: `gobjdump prints information specific to Go in an ELF, PE or Mach-O executable
+built with Go. e.g.
+* Go version, modules and build settings
 * functions and files where they are defined
 * pcsp/pcln of functions
 * safe points of functions
-* local/argument pointer map of functions`,
+* local/argument pointer map of functions, bits named with DWARF variables
+* struct fields and tags
+* method sets of types and the interfaces they implement
+* calls inlined into functions
+* function, source position and inlined calls at a pc
+* the words of the frame of a function at a pc: live pointers, stack objects...
+* Go binaries in a directory tree or an archive
+* goroutines and their stacks in a core file or a running process
+
+<file> is a path, - for the standard input or a member of a tar, tar.gz or zip
+archive given as archive!member, e.g. image.tar!usr/local/bin/app. Archives in
+archives are chained, e.g. image.tar!blobs/sha256/<digest>!usr/local/bin/app.
+
+gobjdump exits with 2 if the file is not a Go binary or built with an
+unsupported version of Go, 3 if the function, type or pc asked for is not found
+and 1 on any other error.
+
+With --format json, the output is the data printed by the command as a JSON
+object {"schema": N, "data": ...}, N being the version of the schema of data.
+With --format jsonl, listings are written one element per line in such an
+object.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case formatText, formatJSON, formatJSONL:
+			default:
+				return fmt.Errorf("invalid format %q, must be text, json or jsonl", format)
+			}
+			// arguments are fine, errors from here on are not usage errors
+			cmd.SilenceUsage = true
+			return nil
+		},
 	}
+	cmd.PersistentFlags().StringVar(&format, "format", formatText, "output format: text, json or jsonl")
 
-	var function string
+	var functions, packages []string
+	var expand bool
+	var all, allFuncs bool
 
-	functionRequried := func(c *cobra.Command) {
-		c.Flags().StringVarP(&function, "function", "f", "", "function (required)")
-		c.MarkFlagRequired("function")
+	functionFlags := func(c *cobra.Command) {
+		c.Flags().StringArrayVarP(&functions, "function", "f", nil, "function name, glob (* and ?) or /regexp/, may be repeated")
+		c.Flags().StringArrayVar(&packages, "pkg", nil, "only functions of this package, all of them without -f, may be repeated")
+		c.Flags().BoolVar(&allFuncs, "all", false, "all functions")
 	}
 
 	requireFile := func(cmd *cobra.Command, args []string) error {
 		if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
 			return err
 		}
-		if _, err := os.Stat(args[0]); err != nil {
-			return err
+		if file, _ := splitPath(args[0]); file != "-" {
+			if _, err := os.Stat(file); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
 
-	doElfFile := func(f string, fn func(*elf.ELF_Info)) {
-		ef := elf.Open(f)
+	requireFuncs := func(cmd *cobra.Command, args []string) error {
+		if len(functions) == 0 && len(packages) == 0 && !allFuncs {
+			return errors.New("one of -f, --pkg or --all is required")
+		}
+		return requireFile(cmd, args)
+	}
+
+	doElfFile := func(f string, fn func(*elf.ELF_Info) (textWriter, error)) error {
+		ef, done, err := openBinary(f)
+		if err != nil {
+			return err
+		}
+		defer done()
 		defer ef.Close()
-		fn(ef)
+		v, err := fn(ef)
+		if err != nil {
+			return err
+		}
+		return write(os.Stdout, format, v)
+	}
+
+	// doFuncs writes the dump of the selected functions, a list unless a
+	// single function is asked for by name.
+	doFuncs := func(f string, dump func(ef *elf.ELF_Info, fn string) (textWriter, error)) error {
+		ef, done, err := openBinary(f)
+		if err != nil {
+			return err
+		}
+		defer done()
+		defer ef.Close()
+		names, err := ef.SelectFuncs(elf.FuncSelector{Patterns: functions, Packages: packages, All: allFuncs})
+		if err != nil {
+			return err
+		}
+		if len(functions) == 1 && len(packages) == 0 && !allFuncs && len(names) == 1 && names[0] == functions[0] {
+			v, err := dump(ef, names[0])
+			if err != nil {
+				return err
+			}
+			return write(os.Stdout, format, v)
+		}
+		return writeEach(os.Stdout, format, len(names), func(i int) (textWriter, error) {
+			return dump(ef, names[i])
+		})
 	}
 
 	cmdPrintModule := &cobra.Command{
 		Use:   "mod  <file>",
 		Short: "print the module data layout",
 		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintModule(os.Stdout)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.ModuleData()
+			})
+		},
+	}
+
+	cmdPrintBuildInfo := &cobra.Command{
+		Use:   "buildinfo <file>",
+		Short: "print the Go version, modules and build settings the binary was built with",
+		Args:  requireFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.BuildInfo()
 			})
 		},
 	}
@@ -56,9 +299,9 @@ func main() {
 		Use:   "func <file>",
 		Short: "print functions grouped by files where they are defined",
 		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintFuncs(os.Stdout)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.FuncsByFile()
 			})
 		},
 	}
@@ -67,101 +310,276 @@ func main() {
 		Use:   "type <file>",
 		Short: "print types that appear in the .typelinks section",
 		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintTypes(os.Stdout)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.Types(expand)
+			})
+		},
+	}
+	cmdPrintTypes.Flags().BoolVarP(&expand, "expand", "x", false, "print the contents of the type descriptors")
+
+	cmdPrintTags := &cobra.Command{
+		Use:   "tags <file>",
+		Short: "print struct types with their field names and tags",
+		Args:  requireFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.StructTags(all)
+			})
+		},
+	}
+	cmdPrintTags.Flags().BoolVarP(&all, "all", "a", false, "print structs without tags as well")
+
+	var typ string
+	cmdPrintMethods := &cobra.Command{
+		Use:   "methods <file>",
+		Short: "print the methods of named types with the functions implementing them",
+		Args:  requireFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.Methods(typ)
+			})
+		},
+	}
+	cmdPrintMethods.Flags().StringVarP(&typ, "type", "t", "", "only print the methods of this type")
+
+	var iface string
+	cmdPrintItabs := &cobra.Command{
+		Use:   "itab <file>",
+		Short: "print the interface tables: concrete type -> interface and the functions implementing its methods",
+		Args:  requireFile,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.Itabs(iface, typ)
 			})
 		},
 	}
+	cmdPrintItabs.Flags().StringVarP(&iface, "iface", "i", "", "only print the itabs of this interface type")
+	cmdPrintItabs.Flags().StringVarP(&typ, "type", "t", "", "only print the itabs of this concrete type")
 
 	cmdPrintPCSP := &cobra.Command{
 		Use:   "pcsp <file>",
-		Short: "print pc->sp of a function",
-		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintPCSP(os.Stdout, function)
+		Short: "print pc->sp of functions",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.PCSP(fn)
 			})
 
 		},
 	}
-	functionRequried(cmdPrintPCSP)
+	functionFlags(cmdPrintPCSP)
 
 	cmdPrintPCLN := &cobra.Command{
 		Use:   "pcln <file>",
-		Short: "print pc->line No. of a function",
-		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintPCLN(os.Stdout, function)
+		Short: "print pc->line No. of functions",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.PCLN(fn)
 			})
 
 		},
 	}
-	functionRequried(cmdPrintPCLN)
+	functionFlags(cmdPrintPCLN)
 
 	cmdPrintSafePoints := &cobra.Command{
 		Use:   "safe <file>",
-		Short: "print safe points of a function",
-		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintSafePoints(os.Stdout, function)
+		Short: "print safe points of functions",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.SafePoints(fn)
 			})
 
 		},
 	}
-	functionRequried(cmdPrintSafePoints)
+	functionFlags(cmdPrintSafePoints)
 
 	cmdPrintArgPointerMap := &cobra.Command{
 		Use:   "ap <file>",
-		Short: "print argument pointer map of a function",
-		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintArgPointerMap(os.Stdout, function)
+		Short: "print argument pointer map of functions",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.ArgPointerMap(fn)
 			})
 
 		},
 	}
-	functionRequried(cmdPrintArgPointerMap)
+	functionFlags(cmdPrintArgPointerMap)
 
 	cmdPrintLocalPointerMap := &cobra.Command{
 		Use:   "lp <file>",
-		Short: "print local pointer map of a function",
-		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintLocalPointerMap(os.Stdout, function)
+		Short: "print local pointer map of functions",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.LocalPointerMap(fn)
 			})
 
 		},
 	}
-	functionRequried(cmdPrintLocalPointerMap)
+	functionFlags(cmdPrintLocalPointerMap)
 
 	cmdPrintStackObjs := &cobra.Command{
 		Use:   "so <file>",
-		Short: "print stack objects of a function",
+		Short: "print stack objects of functions",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.StackObjects(fn)
+			})
+
+		},
+	}
+
+	functionFlags(cmdPrintStackObjs)
+
+	cmdPrintInlTree := &cobra.Command{
+		Use:   "inl <file>",
+		Short: "print the calls inlined into functions and their pc ranges",
+		Args:  requireFuncs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doFuncs(args[0], func(f *elf.ELF_Info, fn string) (textWriter, error) {
+				return f.InlTree(fn)
+			})
+		},
+	}
+	functionFlags(cmdPrintInlTree)
+
+	cmdPrintPC := &cobra.Command{
+		Use:   "pc <file> <addr...>",
+		Short: "print function, file:line, pcsp, stack map index and unsafe point at pcs, with inlined calls as frames",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+				return err
+			}
+			if _, err := parseAddrs(args[1:]); err != nil {
+				return err
+			}
+			return requireFile(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pcs, _ := parseAddrs(args[1:])
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.PCInfos(pcs...)
+			})
+		},
+	}
+
+	var frameFunc, framePC string
+	cmdFrame := &cobra.Command{
+		Use:   "frame <file> -f <func> --pc <addr>",
+		Short: "print the frame of a function at a pc word by word, with the live pointers, stack objects, return address and saved frame pointer",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if frameFunc == "" || framePC == "" {
+				return errors.New("-f and --pc are required")
+			}
+			if _, err := parseAddrs([]string{framePC}); err != nil {
+				return err
+			}
+			return requireFile(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pc, _ := parseAddrs([]string{framePC})
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.FrameLayout(frameFunc, pc[0])
+			})
+		},
+	}
+	cmdFrame.Flags().StringVarP(&frameFunc, "function", "f", "", "function name")
+	cmdFrame.Flags().StringVar(&framePC, "pc", "", "pc in the function, a return address like those of tracebacks")
+
+	cmdScan := &cobra.Command{
+		Use:   "scan <dir|archive>",
+		Short: "list the Go binaries in a directory tree or a tar, tar.gz or zip archive, with their Go version, main module, arch, stripped/PIE status and pclntab layout",
 		Args:  requireFile,
-		Run: func(cmd *cobra.Command, args []string) {
-			doElfFile(args[0], func(f *elf.ELF_Info) {
-				f.PrintStackObjs(os.Stdout, function)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bins, err := scanBinaries(args[0], func(err error) {
+				fmt.Fprintln(os.Stderr, "Warning:", err)
 			})
+			if err != nil {
+				return err
+			}
+			return write(os.Stdout, format, bins)
+		},
+	}
 
+	cmdCore := &cobra.Command{
+		Use:   "core <file> <corefile>",
+		Short: "print the goroutines of a process of the binary dumped in a core file, with their status, wait reason and stack",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+				return err
+			}
+			if _, err := os.Stat(args[1]); err != nil {
+				return err
+			}
+			return requireFile(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := elf.OpenCore(args[1])
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			return doElfFile(args[0], func(f *elf.ELF_Info) (textWriter, error) {
+				return f.Goroutines(c.Process(f))
+			})
 		},
 	}
 
-	functionRequried(cmdPrintStackObjs)
+	cmdProc := &cobra.Command{
+		Use:   "proc <pid>",
+		Short: "print the goroutines of a running process, read through /proc, with their status, wait reason and stack",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return err
+			}
+			if _, err := strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid pid %q", args[0])
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pid, _ := strconv.Atoi(args[0])
+			p, err := elf.OpenProc(pid)
+			if err != nil {
+				return err
+			}
+			defer p.Close()
+			return doElfFile(p.Exe(), func(f *elf.ELF_Info) (textWriter, error) {
+				pr, err := p.Process(f)
+				if err != nil {
+					return nil, err
+				}
+				return f.Goroutines(pr)
+			})
+		},
+	}
 
 	cmd.AddCommand(cmdPrintModule)
+	cmd.AddCommand(cmdPrintBuildInfo)
 	cmd.AddCommand(cmdPrintFuncs)
 	cmd.AddCommand(cmdPrintTypes)
+	cmd.AddCommand(cmdPrintTags)
+	cmd.AddCommand(cmdPrintMethods)
+	cmd.AddCommand(cmdPrintItabs)
 	cmd.AddCommand(cmdPrintPCSP)
 	cmd.AddCommand(cmdPrintPCLN)
 	cmd.AddCommand(cmdPrintSafePoints)
 	cmd.AddCommand(cmdPrintArgPointerMap)
 	cmd.AddCommand(cmdPrintLocalPointerMap)
 	cmd.AddCommand(cmdPrintStackObjs)
+	cmd.AddCommand(cmdPrintInlTree)
+	cmd.AddCommand(cmdPrintPC)
+	cmd.AddCommand(cmdFrame)
+	cmd.AddCommand(cmdScan)
+	cmd.AddCommand(cmdCore)
+	cmd.AddCommand(cmdProc)
 
-	cmd.Execute()
+	if err := cmd.Execute(); err != nil {
+		os.Exit(exitCode(err))
+	}
 }
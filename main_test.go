@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWriteEachError checks that writeEach waits for the dumps being
+// computed when one fails, as the binary is closed once it returns.
+func TestWriteEachError(t *testing.T) {
+	fail := errors.New("fail")
+	var running int32
+	err := writeEach(io.Discard, formatText, 64, func(i int) (textWriter, error) {
+		atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		if i == 0 {
+			return nil, fail
+		}
+		time.Sleep(10 * time.Millisecond)
+		return list{}, nil
+	})
+	if err != fail {
+		t.Errorf("got %v, want %v", err, fail)
+	}
+	if n := atomic.LoadInt32(&running); n != 0 {
+		t.Errorf("%d dumps still running", n)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/voidpx/gobjdump/elf"
+)
+
+// openBinary opens the Go binary name: a file, - for the standard input or a
+// member of a tar, tar.gz or zip archive given as archive!member, e.g.
+// image.tar!usr/local/bin/app. Archives in archives are chained, e.g.
+// image.tar!blobs/sha256/0123...!usr/local/bin/app. done closes the archive
+// once the binary is closed.
+func openBinary(name string) (ef *elf.ELF_Info, done func(), err error) {
+	if file, members := splitPath(name); file != "-" && len(members) == 0 {
+		if ef, err = elf.Open(file); err != nil {
+			return nil, nil, err
+		}
+		return ef, func() {}, nil
+	}
+	r, size, done, err := openReader(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ef, err = elf.OpenReader(r, size); err != nil {
+		done()
+		return nil, nil, err
+	}
+	return ef, done, nil
+}
+
+// openReader opens the file name like openBinary, done closes it.
+func openReader(name string) (r io.ReaderAt, size int64, done func(), err error) {
+	file, members := splitPath(name)
+	done = func() {}
+	if file == "-" {
+		if r, size, err = stdin(); err != nil {
+			return nil, 0, nil, err
+		}
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, nil, err
+		}
+		r, size, done = f, fi.Size(), func() { f.Close() }
+	}
+	for i, m := range members {
+		if r, size, err = openMember(r, size, m); err != nil {
+			done()
+			return nil, 0, nil, fmt.Errorf("%s: %w", strings.Join(append([]string{file}, members[:i]...), "!"), err)
+		}
+	}
+	return r, size, done, nil
+}
+
+// splitPath splits name into the file to open and the members of the
+// archives in it, taking the shortest prefix of name that is - or a file
+// unless name itself is one.
+func splitPath(name string) (file string, members []string) {
+	if name == "-" || !strings.Contains(name, "!") || exists(name) {
+		return name, nil
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '!' && (name[:i] == "-" || exists(name[:i])) {
+			return name[:i], strings.Split(name[i+1:], "!")
+		}
+	}
+	return name, nil
+}
+
+func exists(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
+
+// stdin returns the standard input, read in memory unless it is a file.
+func stdin() (io.ReaderAt, int64, error) {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode().IsRegular() {
+		return os.Stdin, fi.Size(), nil
+	}
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
+
+// maxLinks is the number of links followed to a member of a tar archive.
+const maxLinks = 16
+
+// openMember returns the member name of the archive made of the size bytes
+// of r, picking its format from its magic. Members stored as is are read
+// from r, others are read in memory.
+func openMember(r io.ReaderAt, size int64, name string) (io.ReaderAt, int64, error) {
+	var magic [magicLen]byte
+	n, _ := r.ReadAt(magic[:], 0)
+	switch archiveFormat(magic[:n]) {
+	case "zip":
+		return zipMember(r, size, name)
+	case "tar.gz":
+		return tarMember(r, size, name, true)
+	case "tar":
+		return tarMember(r, size, name, false)
+	}
+	return nil, 0, errors.New("not a tar, tar.gz or zip archive")
+}
+
+// magicLen is the length of the start of a file telling its format.
+const magicLen = 262
+
+// archiveFormat returns the format of the archive starting with magic: zip,
+// tar.gz or tar, "" if it is not an archive.
+func archiveFormat(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		return "zip"
+	case bytes.HasPrefix(magic, []byte("\x1f\x8b")):
+		return "tar.gz"
+	case len(magic) >= magicLen && string(magic[257:magicLen]) == "ustar":
+		return "tar"
+	}
+	return ""
+}
+
+// memberPath cleans the path of a member, dropping any leading ./ or /.
+func memberPath(name string) string {
+	return path.Clean("/" + name)[1:]
+}
+
+func zipMember(r io.ReaderAt, size int64, name string) (io.ReaderAt, int64, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, f := range zr.File {
+		if memberPath(f.Name) != memberPath(name) || f.FileInfo().IsDir() {
+			continue
+		}
+		if f.Method == zip.Store {
+			off, err := f.DataOffset()
+			if err != nil {
+				return nil, 0, err
+			}
+			return io.NewSectionReader(r, off, int64(f.UncompressedSize64)), int64(f.UncompressedSize64), nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, 0, err
+		}
+		defer rc.Close()
+		return readMember(rc, name)
+	}
+	return nil, 0, fmt.Errorf("no member %s", name)
+}
+
+// tarMember returns the member name of a tar archive, following links to
+// it. The archive is read again from the start for each link.
+func tarMember(r io.ReaderAt, size int64, name string, gzipped bool) (io.ReaderAt, int64, error) {
+	want := memberPath(name)
+	for links := 0; links <= maxLinks; links++ {
+		sr := io.NewSectionReader(r, 0, size)
+		tr := tar.NewReader(sr)
+		if gzipped {
+			zr, err := gzip.NewReader(sr)
+			if err != nil {
+				return nil, 0, err
+			}
+			tr = tar.NewReader(zr)
+		}
+		var hdr *tar.Header
+		for {
+			var err error
+			hdr, err = tr.Next()
+			if err == io.EOF {
+				return nil, 0, fmt.Errorf("no member %s", name)
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			if memberPath(hdr.Name) == want {
+				break
+			}
+		}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if !gzipped && !sparse(hdr) {
+				// the data follows the header
+				off, err := sr.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return nil, 0, err
+				}
+				return io.NewSectionReader(r, off, hdr.Size), hdr.Size, nil
+			}
+			return readMember(tr, name)
+		case tar.TypeLink:
+			want = memberPath(hdr.Linkname)
+		case tar.TypeSymlink:
+			if path.IsAbs(hdr.Linkname) {
+				want = memberPath(hdr.Linkname)
+			} else {
+				want = memberPath(path.Join(path.Dir(want), hdr.Linkname))
+			}
+		case tar.TypeDir:
+			return nil, 0, fmt.Errorf("%s is a directory", name)
+		default:
+			return readMember(tr, name)
+		}
+	}
+	return nil, 0, fmt.Errorf("too many links to %s", name)
+}
+
+// sparse reports whether the data of hdr is stored in the PAX format for
+// sparse files, in which case it is not the file as is.
+func sparse(hdr *tar.Header) bool {
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+func readMember(r io.Reader, name string) (io.ReaderAt, int64, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading %s: %w", name, err)
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
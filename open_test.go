@@ -0,0 +1,158 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tarFile(t *testing.T, gzipped bool, files map[string][]byte, links map[string]string) []byte {
+	var buf bytes.Buffer
+	var zw *gzip.Writer
+	tw := tar.NewWriter(&buf)
+	if gzipped {
+		zw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(zw)
+	}
+	for name, b := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(b))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, to := range links {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: to}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func zipFile(t *testing.T, method uint16, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, b := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestOpenBinary opens the test binary from archives.
+func TestOpenBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	b, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, done, err := openBinary(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	defer want.Close()
+
+	dir := t.TempDir()
+	app := map[string][]byte{"./usr/local/bin/app": b}
+	for name, data := range map[string][]byte{
+		"image.tar":    tarFile(t, false, app, map[string]string{"usr/bin/app": "../local/bin/app"}),
+		"image.tar.gz": tarFile(t, true, app, nil),
+		"store.zip":    zipFile(t, zip.Store, app),
+		"deflate.zip":  zipFile(t, zip.Deflate, app),
+		"nested.tar":   tarFile(t, false, map[string][]byte{"blobs/sha256/0123": tarFile(t, true, app, nil)}, nil),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{
+		"image.tar!usr/local/bin/app",
+		"image.tar!/usr/bin/app",
+		"image.tar.gz!usr/local/bin/app",
+		"store.zip!usr/local/bin/app",
+		"deflate.zip!./usr/local/bin/app",
+		"nested.tar!blobs/sha256/0123!usr/local/bin/app",
+	} {
+		f, done, err := openBinary(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if n, w := f.Module().NumFunc(), want.Module().NumFunc(); n != w {
+			t.Errorf("%s: %d functions, want %d", name, n, w)
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		done()
+	}
+	for _, name := range []string{
+		"image.tar!usr/local/bin/nope",
+		"image.tar!usr/local",
+		"store.zip!usr/local/bin/app!app",
+	} {
+		if f, done, err := openBinary(filepath.Join(dir, name)); err == nil {
+			f.Close()
+			done()
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.tar", "b!c.zip"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		name    string
+		file    string
+		members []string
+	}{
+		{"-", "-", nil},
+		{"-!app", "-", []string{"app"}},
+		{dir + "/a.tar", dir + "/a.tar", nil},
+		{dir + "/a.tar!x!y", dir + "/a.tar", []string{"x", "y"}},
+		{dir + "/b!c.zip", dir + "/b!c.zip", nil},
+		{dir + "/b!c.zip!x", dir + "/b!c.zip", []string{"x"}},
+		{dir + "/nope!x", dir + "/nope!x", nil},
+	} {
+		file, members := splitPath(c.name)
+		if file != c.file || len(members) != len(c.members) {
+			t.Errorf("%s: %s %q, want %s %q", c.name, file, members, c.file, c.members)
+			continue
+		}
+		for i := range members {
+			if members[i] != c.members[i] {
+				t.Errorf("%s: %s %q, want %s %q", c.name, file, members, c.file, c.members)
+			}
+		}
+	}
+}
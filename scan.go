@@ -0,0 +1,268 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/voidpx/gobjdump/elf"
+)
+
+// maxDepth is the depth of the archives in archives scan looks into.
+const maxDepth = 8
+
+// candidate is a file found by scan that may be a Go binary, read from r if
+// it is a member of an archive and from path otherwise.
+type candidate struct {
+	path string
+	r    io.ReaderAt
+	size int64
+}
+
+// scanBinaries returns the Go binaries in root, a directory tree or an
+// archive given like to openBinary, in the order they are found. Files that
+// cannot be read are passed to warn and skipped.
+func scanBinaries(root string, warn func(error)) (elf.Binaries, error) {
+	type job struct {
+		i int
+		c candidate
+	}
+	type result struct {
+		i int
+		b *elf.Binary
+	}
+	jobs := make(chan job)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- result{j.i, summarize(j.c)}
+			}
+		}()
+	}
+	// err and done are set before jobs is closed, results after that
+	done := func() {}
+	var err error
+	go func() {
+		defer close(jobs)
+		n := 0
+		found := func(c candidate) {
+			jobs <- job{n, c}
+			n++
+		}
+		if fi, serr := os.Stat(root); serr == nil && fi.IsDir() {
+			err = walkDir(root, found, warn)
+			return
+		}
+		var r io.ReaderAt
+		var size int64
+		if r, size, done, err = openReader(root); err != nil {
+			done = func() {}
+			return
+		}
+		err = walkArchive(root, r, size, 0, found, warn)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	var rs []result
+	for r := range results {
+		if r.b != nil {
+			rs = append(rs, r)
+		}
+	}
+	// the binaries read from the archive are all summarized
+	done()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].i < rs[j].i
+	})
+	bins := elf.Binaries{}
+	for _, r := range rs {
+		bins = append(bins, *r.b)
+	}
+	return bins, nil
+}
+
+// summarize returns the summary of the binary c, nil if it is not a Go
+// binary.
+func summarize(c candidate) *elf.Binary {
+	var ef *elf.ELF_Info
+	var err error
+	if c.r == nil {
+		ef, err = elf.Open(c.path)
+	} else {
+		ef, err = elf.OpenReader(c.r, c.size)
+	}
+	if errors.Is(err, elf.ErrNotGoBinary) {
+		return nil
+	}
+	if err != nil {
+		return &elf.Binary{Path: c.path, Error: err.Error()}
+	}
+	defer ef.Close()
+	b := ef.Summary()
+	b.Path = c.path
+	return b
+}
+
+// walkDir calls found for the object files in the directory tree root,
+// without following symbolic links.
+func walkDir(root string, found func(candidate), warn func(error)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			warn(err)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			warn(err)
+			return nil
+		}
+		var magic [4]byte
+		n, _ := f.ReadAt(magic[:], 0)
+		f.Close()
+		if elf.IsObjFile(magic[:n]) {
+			found(candidate{path: path})
+		}
+		return nil
+	})
+}
+
+// walkArchive calls found for the object files in the archive name made of
+// the size bytes of r, and in the archives in it down to maxDepth. A file
+// that is not an archive is taken as the only file in it.
+func walkArchive(name string, r io.ReaderAt, size int64, depth int, found func(candidate), warn func(error)) error {
+	var magic [magicLen]byte
+	n, _ := r.ReadAt(magic[:], 0)
+	format := archiveFormat(magic[:n])
+	if format == "" {
+		if elf.IsObjFile(magic[:n]) {
+			found(candidate{name, r, size})
+		}
+		return nil
+	}
+	if depth == maxDepth {
+		warn(fmt.Errorf("%s: archives nested too deep", name))
+		return nil
+	}
+	// member looks into a regular file of the archive, read from at if it
+	// is stored as is and else from stream.
+	member := func(path string, at io.ReaderAt, stream io.Reader, size int64) error {
+		path = name + "!" + memberPath(path)
+		head := make([]byte, magicLen)
+		var n int
+		if at != nil {
+			n, _ = at.ReadAt(head, 0)
+		} else {
+			var err error
+			if n, err = io.ReadFull(stream, head); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+				return err
+			}
+		}
+		head = head[:n]
+		if !elf.IsObjFile(head) && archiveFormat(head) == "" {
+			return nil
+		}
+		if at == nil {
+			rest, err := io.ReadAll(stream)
+			if err != nil {
+				return err
+			}
+			b := append(head, rest...)
+			at, size = bytes.NewReader(b), int64(len(b))
+		}
+		if elf.IsObjFile(head) {
+			found(candidate{path, at, size})
+			return nil
+		}
+		if err := walkArchive(path, at, size, depth+1, found, warn); err != nil {
+			warn(err)
+		}
+		return nil
+	}
+	if format == "zip" {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			size := int64(f.UncompressedSize64)
+			if f.Method == zip.Store {
+				off, err := f.DataOffset()
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				err = member(f.Name, io.NewSectionReader(r, off, size), nil, size)
+			} else {
+				var rc io.ReadCloser
+				if rc, err = f.Open(); err == nil {
+					err = member(f.Name, nil, rc, size)
+					rc.Close()
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("%s!%s: %w", name, memberPath(f.Name), err)
+			}
+		}
+		return nil
+	}
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+	if format == "tar.gz" {
+		zr, err := gzip.NewReader(sr)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		tr = tar.NewReader(zr)
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if format == "tar" && !sparse(hdr) {
+			off, err := sr.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			err = member(hdr.Name, io.NewSectionReader(r, off, hdr.Size), nil, hdr.Size)
+		} else {
+			err = member(hdr.Name, nil, tr, hdr.Size)
+		}
+		if err != nil {
+			return fmt.Errorf("%s!%s: %w", name, memberPath(hdr.Name), err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestScanBinaries(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	b, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"bin/app":      b,
+		"lib/app.tgz":  tarFile(t, true, map[string][]byte{"app": b}, nil),
+		"lib/notes.md": []byte("MZ, not a binary"),
+		"lib/sh":       []byte("\x7fELF, not a binary either"),
+	}
+	for name, data := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "image.tar"), tarFile(t, false, files, nil), 0644); err != nil {
+		t.Fatal(err)
+	}
+	warn := func(err error) {
+		t.Error(err)
+	}
+	bins, err := scanBinaries(filepath.Join(dir, "bin"), warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bins) != 1 || bins[0].Path != filepath.Join(dir, "bin/app") || bins[0].GoVersion == "" || bins[0].Error != "" {
+		t.Errorf("scan of bin: %+v", bins)
+	}
+	bins, err = scanBinaries(filepath.Join(dir, "image.tar"), warn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]bool{}
+	for _, b := range bins {
+		paths[b.Path] = true
+		if b.GoVersion == "" || b.Error != "" {
+			t.Errorf("%+v", b)
+		}
+	}
+	for _, p := range []string{"image.tar!bin/app", "image.tar!lib/app.tgz!app"} {
+		if !paths[filepath.Join(dir, p)] {
+			t.Errorf("%s not found in %+v", p, bins)
+		}
+	}
+	if len(bins) != 2 {
+		t.Errorf("scan of image.tar: %+v", bins)
+	}
+}
+
+func TestScanStdCommand(t *testing.T) {
+	// commands of the distribution are built without a main module
+	goroot, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		t.Skip(err)
+	}
+	gofmt := filepath.Join(strings.TrimSpace(string(goroot)), "bin", "gofmt")
+	if runtime.GOOS == "windows" {
+		gofmt += ".exe"
+	}
+	if _, err := os.Stat(gofmt); err != nil {
+		t.Skip(err)
+	}
+	bins, err := scanBinaries(gofmt, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bins) != 1 || bins[0].Main != "cmd/gofmt" {
+		t.Errorf("scan of %s: %+v", gofmt, bins)
+	}
+}